@@ -0,0 +1,87 @@
+package delta
+
+import (
+	"slices"
+	"sort"
+)
+
+// Alternatives returns up to n diffs of the two revisions, all of them as
+// short or nearly as short as the one of Calculate, ranked by their
+// Readability, best first, so review tools can offer another view when the
+// first doesn't read well. Besides the diff of Calculate, they're made by
+// matching repeated words with their first occurrence rather than their
+// last, and by weighting words like WithRarityWeighting; the ones changing
+// more than a quarter more words than Calculate are left out, and so are
+// duplicates. It returns all of them when n is 0 or less.
+func Alternatives(prev, curr string, n int, plaintext bool, opts ...Option) []string {
+	o := newOptions(plaintext, opts)
+	p, c := o.tokenize(prev), o.tokenize(curr)
+
+	weighted := newOptions(plaintext, append(slices.Clip(opts), WithRarityWeighting()))
+	candidates := [][]Edit[string]{
+		o.diff(p, c),
+		reversed(o.diff(reverse(p), reverse(c))),
+		weighted.diff(p, c),
+		reversed(weighted.diff(reverse(p), reverse(c))),
+	}
+
+	type alternative struct {
+		edits []Edit[string]
+		score float64
+	}
+	var alternatives []alternative
+	limit := cost(candidates[0]) * 5 / 4
+	for _, edits := range candidates {
+		if cost(edits) > limit || slices.ContainsFunc(alternatives, func(a alternative) bool {
+			return slices.EqualFunc(a.edits, edits, func(a, b Edit[string]) bool {
+				return a.Op == b.Op && slices.Equal(a.Items, b.Items)
+			})
+		}) {
+			continue
+		}
+		alternatives = append(alternatives, alternative{edits, Readability(edits)})
+	}
+	sort.SliceStable(alternatives, func(i, j int) bool {
+		return alternatives[i].score > alternatives[j].score
+	})
+	if n > 0 && len(alternatives) > n {
+		alternatives = alternatives[:n]
+	}
+
+	diffs := make([]string, len(alternatives))
+	for i, a := range alternatives {
+		diffs[i] = newOptions(plaintext, opts).output(a.edits, p, c)
+	}
+	return diffs
+}
+
+// cost counts the tokens the edits insert and delete.
+func cost(edits []Edit[string]) int {
+	n := 0
+	for _, e := range edits {
+		if e.Op != Equal {
+			n += len(e.Items)
+		}
+	}
+	return n
+}
+
+// reverse returns the tokens in reverse order.
+func reverse(tokens []string) []string {
+	r := slices.Clone(tokens)
+	slices.Reverse(r)
+	return r
+}
+
+// reversed puts the edits of reversed sequences back in order, keeping
+// deletions before the insertions replacing them.
+func reversed(edits []Edit[string]) []Edit[string] {
+	slices.Reverse(edits)
+	for i := range edits {
+		edits[i].Items = reverse(edits[i].Items)
+		if i > 0 && edits[i-1].Op == Insert && edits[i].Op == Delete {
+			edits[i-1], edits[i] = edits[i], edits[i-1]
+		}
+	}
+	return edits
+}
@@ -0,0 +1,70 @@
+package delta
+
+// WithAnchors marks the tokens for which anchor returns true as landmarks,
+// such as section numbers or ids, which have to line up between the
+// revisions. Anchors found in both are matched up first, in order, and the
+// text between them is only compared to the text between the matching ones,
+// so changes never swallow a landmark both revisions share. The function is
+// given tokens as they're compared, that is normalized, and whole lines for
+// SideBySide and Unified.
+//
+//	delta.Calculate("§1 a b §2 c", "§1 c §2 a b", true, delta.WithAnchors(func(w string) bool {
+//		return strings.HasPrefix(w, "§")
+//	}))
+//		// "§1 ---a b--- +++c+++ §2 ---c--- +++a b+++"
+func WithAnchors(anchor func(token string) bool) Option {
+	return func(o *options) {
+		o.anchor = anchor
+	}
+}
+
+// anchored diffs the sequences between the anchors they have in common.
+func (o *options) anchored(prev, curr []string) []Edit[string] {
+	var pa, ca []int
+	var pk, ck []string
+	for i, w := range prev {
+		if o.anchor(w) {
+			pa, pk = append(pa, i), append(pk, w)
+		}
+	}
+	for j, w := range curr {
+		if o.anchor(w) {
+			ca, ck = append(ca, j), append(ck, w)
+		}
+	}
+	if len(pa) == 0 || len(ca) == 0 {
+		return o.align(prev, curr)
+	}
+
+	var edits []Edit[string]
+	i, j, a, b := 0, 0, 0, 0
+	for _, e := range DiffSlices(pk, ck) {
+		switch e.Op {
+		case Delete:
+			a += len(e.Items)
+		case Insert:
+			b += len(e.Items)
+		case Equal:
+			for range e.Items {
+				edits = appendEdits(edits, o.align(prev[i:pa[a]], curr[j:ca[b]])...)
+				edits = appendEdits(edits, Edit[string]{Equal, curr[ca[b] : ca[b]+1]})
+				i, j, a, b = pa[a]+1, ca[b]+1, a+1, b+1
+			}
+		}
+	}
+	return appendEdits(edits, o.align(prev[i:], curr[j:])...)
+}
+
+// appendEdits appends the edits, merging the first one into the last one
+// already there when they share the operation.
+func appendEdits(edits []Edit[string], more ...Edit[string]) []Edit[string] {
+	for _, e := range more {
+		if n := len(edits); n > 0 && edits[n-1].Op == e.Op {
+			last := edits[n-1].Items
+			edits[n-1].Items = append(last[:len(last):len(last)], e.Items...)
+			continue
+		}
+		edits = append(edits, e)
+	}
+	return edits
+}
@@ -0,0 +1,154 @@
+package delta
+
+import (
+	"slices"
+	"strings"
+)
+
+// Revision is the text of a revision along with who made it.
+type Revision struct {
+	Text   string
+	Author string
+}
+
+// Change is a run of text which was kept, inserted or deleted between two
+// revisions, along with its author: whoever made the current revision for
+// insertions and deletions, and whoever made the previous one for the text
+// which stayed the same. Kind tells what sort of change it's part of, and is
+// empty for text which stayed the same.
+type Change struct {
+	Op     Operation `json:"op"`
+	Text   string    `json:"text"`
+	Author string    `json:"author,omitempty"`
+	Kind   Kind      `json:"kind,omitempty"`
+}
+
+// Changes compares the two revisions like Calculate, and returns the
+// changes with their authors and kinds, for building views of who changed
+// what, or filtering changes by kind. Line breaks are kept as such in the
+// text of the changes.
+//
+//	delta.Changes(delta.Revision{"hello world", "ann"}, delta.Revision{"hello earth", "bob"})
+//		// []Change{{Equal, "hello", "ann", ""}, {Delete, "world", "bob", Replacement}, {Insert, "earth", "bob", Replacement}}
+func Changes(prev, curr Revision, opts ...Option) []Change {
+	o := newOptions(false, opts)
+	p, c := o.tokenize(prev.Text), o.tokenize(curr.Text)
+
+	edits := o.arrange(o.diff(p, c))
+	kinds := o.categorize(edits)
+	var changes []Change
+	for n, e := range edits {
+		author := curr.Author
+		if e.Op == Equal {
+			author = prev.Author
+		}
+		changes = append(changes, Change{e.Op, o.join(e.Items), author, kinds[n]})
+	}
+	return changes
+}
+
+// WithAuthor names who made the current revision. In HTML every change
+// gets a data-author attribute with the name, while plain text follows
+// every change with the name in brackets, as in "+++earth+++[bob]".
+func WithAuthor(name string) Option {
+	return func(o *options) {
+		o.author = name
+	}
+}
+
+// signature returns what follows the closing marker of a change made by
+// the given author in plain text to tell who made it.
+func (o *options) signature(author string) string {
+	if author == "" || !o.plaintext {
+		return ""
+	}
+	return "[" + o.escape(author) + "]"
+}
+
+// Combined shows the changes several authors made to the same base at once,
+// such as the edits of several reviewers of a draft, with every change
+// attributed to whoever made it like WithAuthor does, so pages can color
+// them by author with CSS such as ins[data-author="ann"]. Words deleted by
+// several of them, or inserted by several of them at the same place, name
+// them all, separated by commas. Insertions at the same place follow the
+// order of the revisions.
+//
+//	delta.Combined("hello world", []delta.Revision{{"hello earth", "ann"}, {"hello world again", "bob"}}, true)
+//		// "hello ---world---[ann] +++earth+++[ann] +++again+++[bob]"
+func Combined(base string, revisions []Revision, plaintext bool, opts ...Option) string {
+	o := newOptions(plaintext, opts)
+	b := o.tokenize(base)
+
+	// Every revision inserts at boundaries between the words of the base,
+	// and deletes words of it.
+	type insertion struct {
+		items   []string
+		authors []string
+	}
+	inserted := make([][]insertion, len(b)+1)
+	deleted := make([][]string, len(b))
+	for _, r := range revisions {
+		i := 0
+		edits, covered := o.covering(b, o.tokenize(r.Text))
+		var space []string
+		for n, e := range edits {
+			switch e.Op {
+			case Equal:
+				i += covered[n]
+				// With WithReflow the whitespace before an insertion
+				// can end the unchanged text of the revision, where
+				// the base has none.
+				space = nil
+				for k := len(e.Items); k > 0 && o.blank(e.Items[k-1]) && !o.blank(b[i-1]); k-- {
+					space = e.Items[k-1:]
+				}
+			case Delete:
+				space = nil
+				for range e.Items {
+					deleted[i] = append(deleted[i], r.Author)
+					i++
+				}
+			case Insert:
+				if space != nil {
+					e.Items = append(slices.Clip(space), e.Items...)
+					space = nil
+				}
+				same := false
+				for k, ins := range inserted[i] {
+					if slices.Equal(ins.items, e.Items) {
+						inserted[i][k].authors = append(ins.authors, r.Author)
+						same = true
+						break
+					}
+				}
+				if !same {
+					inserted[i] = append(inserted[i], insertion{e.Items, []string{r.Author}})
+				}
+			}
+		}
+	}
+
+	var changes []Edit[string]
+	add := func(op Operation, author string, items ...string) {
+		if n := len(changes); n > 0 && changes[n-1].Op == op && o.authors[n-1] == author {
+			changes[n-1].Items = append(changes[n-1].Items, items...)
+			return
+		}
+		changes = append(changes, Edit[string]{op, append([]string(nil), items...)})
+		o.authors = append(o.authors, author)
+	}
+	for k := range inserted {
+		for _, ins := range inserted[k] {
+			add(Insert, strings.Join(ins.authors, ", "), ins.items...)
+		}
+		if k == len(b) {
+			break
+		}
+		if deleted[k] == nil {
+			add(Equal, "", b[k])
+		} else {
+			add(Delete, strings.Join(deleted[k], ", "), b[k])
+		}
+	}
+	return rendered(changes, o)
+}
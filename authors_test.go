@@ -0,0 +1,36 @@
+package delta
+
+import "testing"
+
+func TestCombined(t *testing.T) {
+	tests := []struct {
+		base      string
+		revisions []Revision
+		opts      []Option
+		want      string
+	}{
+		{
+			"hello world",
+			[]Revision{{"hello earth", "ann"}, {"hello world again", "bob"}},
+			nil,
+			"hello ---world---[ann] +++earth+++[ann] +++again+++[bob]",
+		},
+		{
+			"a b\nc d",
+			[]Revision{{"a\nb  c d", "x"}, {"a b\nc e", "y"}},
+			[]Option{WithReflow()},
+			"a b\nc ---d---[y] +++e+++[y]",
+		},
+		{
+			"a\tb  c\n",
+			[]Revision{{"  a b\tc", "x"}, {"a\tb  c\n z", "y"}},
+			[]Option{WithReflow()},
+			"---a\tb  c---[x] +++a b\tc+++[x] +++z+++[y]",
+		},
+	}
+	for _, tt := range tests {
+		if got := Combined(tt.base, tt.revisions, true, tt.opts...); got != tt.want {
+			t.Errorf("Combined(%q, %q) = %q, want %q", tt.base, tt.revisions, got, tt.want)
+		}
+	}
+}
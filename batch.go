@@ -0,0 +1,61 @@
+package delta
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// Pair is a previous and a current revision to compare.
+type Pair struct {
+	Prev, Curr string
+}
+
+// Result is the diff of a pair of revisions compared by CalculateAll, or
+// the error comparing them.
+type Result struct {
+	Diff string
+	Err  error
+}
+
+// CalculateAll compares every pair of revisions like Calculate, several at
+// a time, and returns the results in the order of the pairs. It's meant for
+// reports going over thousands of documents at once, where a pair which
+// can't be compared shouldn't take the whole report down: a panic comparing
+// it is returned as the error of its result instead. As many pairs are
+// compared at a time as Go runs goroutines in parallel, so callbacks given
+// in the options, such as to WithMetrics, have to be safe to call from
+// several goroutines.
+func CalculateAll(pairs []Pair, plaintext bool, opts ...Option) []Result {
+	results := make([]Result, len(pairs))
+	next := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < min(runtime.GOMAXPROCS(0), len(pairs)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i] = calculatePair(pairs[i], plaintext, opts)
+			}
+		}()
+	}
+	for i := range pairs {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	return results
+}
+
+// calculatePair compares a pair for CalculateAll.
+func calculatePair(pair Pair, plaintext bool, opts []Option) (r Result) {
+	defer func() {
+		if err := recover(); err != nil {
+			r.Err = fmt.Errorf("delta: comparing revisions: %v", err)
+		}
+	}()
+	r.Diff = Calculate(pair.Prev, pair.Curr, plaintext, opts...)
+	return r
+}
@@ -0,0 +1,21 @@
+package delta
+
+import "testing"
+
+func TestCalculateAll(t *testing.T) {
+	pairs := []Pair{{"hello world", "hello earth"}, {"a", "a"}, {"a b", "b a"}}
+	results := CalculateAll(pairs, true)
+	if len(results) != len(pairs) {
+		t.Fatalf("CalculateAll returned %d results, want %d", len(results), len(pairs))
+	}
+	for i, p := range pairs {
+		if want := Calculate(p.Prev, p.Curr, true); results[i].Diff != want || results[i].Err != nil {
+			t.Errorf("result %d = %+v, want %q", i, results[i], want)
+		}
+	}
+
+	panicky := WithTokenizer(func(string) []string { panic("boom") })
+	if r := CalculateAll(pairs[:1], true, panicky); r[0].Err == nil {
+		t.Errorf("result = %+v, want an error", r[0])
+	}
+}
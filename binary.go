@@ -0,0 +1,175 @@
+package delta
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// ErrCorrupt is returned when binary encoded patches or edits can't be read.
+var ErrCorrupt = errors.New("delta: corrupt encoding")
+
+// binaryVersion starts every binary encoding, leaving room for others.
+const binaryVersion = 1
+
+// MarshalBinary encodes the patch compactly, for storing a great many of
+// them: the position of every hunk is given relative to the one before, and
+// every text by its length, all as varints. Gob uses it as well.
+func (p Patch) MarshalBinary() ([]byte, error) {
+	b := []byte{binaryVersion}
+	b = binary.AppendUvarint(b, uint64(len(p.Hunks)))
+	last := 0
+	for _, h := range p.Hunks {
+		b = binary.AppendVarint(b, int64(h.Pos-last))
+		last = h.Pos
+		for _, text := range []string{h.Before, h.Delete, h.Insert, h.After} {
+			b = appendString(b, text)
+		}
+	}
+	return b, nil
+}
+
+// UnmarshalBinary decodes a patch encoded by MarshalBinary.
+func (p *Patch) UnmarshalBinary(data []byte) error {
+	d := decoder{data: data}
+	d.version()
+	n := d.count(5)
+	hunks := make([]Hunk, n)
+	last := 0
+	for i := range hunks {
+		h := &hunks[i]
+		h.Pos = last + int(d.varint())
+		last = h.Pos
+		h.Before, h.Delete, h.Insert, h.After = d.string(), d.string(), d.string(), d.string()
+	}
+	if err := d.done(); err != nil {
+		return err
+	}
+	p.Hunks = hunks
+	return nil
+}
+
+// MarshalEdits encodes the edits compactly, like Patch.MarshalBinary: the
+// operation of every edit as a byte, followed by its items, each given by
+// its length as a varint.
+func MarshalEdits(edits []Edit[string]) []byte {
+	b := []byte{binaryVersion}
+	b = binary.AppendUvarint(b, uint64(len(edits)))
+	for _, e := range edits {
+		b = append(b, byte(e.Op))
+		b = binary.AppendUvarint(b, uint64(len(e.Items)))
+		for _, item := range e.Items {
+			b = appendString(b, item)
+		}
+	}
+	return b
+}
+
+// UnmarshalEdits decodes edits encoded by MarshalEdits.
+func UnmarshalEdits(data []byte) ([]Edit[string], error) {
+	d := decoder{data: data}
+	d.version()
+	edits := make([]Edit[string], d.count(2))
+	for i := range edits {
+		edits[i].Op = Operation(d.byte())
+		if edits[i].Op != Equal && edits[i].Op != Insert && edits[i].Op != Delete {
+			d.fail()
+		}
+		edits[i].Items = make([]string, d.count(1))
+		for j := range edits[i].Items {
+			edits[i].Items[j] = d.string()
+		}
+	}
+	if err := d.done(); err != nil {
+		return nil, err
+	}
+	return edits, nil
+}
+
+// appendString appends the length of the text, then the text.
+func appendString(b []byte, text string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(text)))
+	return append(b, text...)
+}
+
+// decoder reads binary encodings. Once anything goes wrong it only returns
+// zeros, and done tells about it.
+type decoder struct {
+	data []byte
+	err  error
+}
+
+// fail marks the data as corrupt.
+func (d *decoder) fail() {
+	d.err, d.data = ErrCorrupt, nil
+}
+
+// version checks the version the data starts with.
+func (d *decoder) version() {
+	if d.byte() != binaryVersion {
+		d.fail()
+	}
+}
+
+// byte reads a single byte.
+func (d *decoder) byte() byte {
+	if len(d.data) == 0 {
+		d.fail()
+		return 0
+	}
+	b := d.data[0]
+	d.data = d.data[1:]
+	return b
+}
+
+// uvarint reads an unsigned varint.
+func (d *decoder) uvarint() uint64 {
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+// varint reads a signed varint.
+func (d *decoder) varint() int64 {
+	v, n := binary.Varint(d.data)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+// count reads how many things follow, each taking at least size bytes, so
+// corrupt data can't make it allocate more than the data could hold.
+func (d *decoder) count(size int) int {
+	n := d.uvarint()
+	if n > uint64(len(d.data)/size) {
+		d.fail()
+		return 0
+	}
+	return int(n)
+}
+
+// string reads a text and its length.
+func (d *decoder) string() string {
+	n := d.uvarint()
+	if n > uint64(len(d.data)) {
+		d.fail()
+		return ""
+	}
+	s := string(d.data[:n])
+	d.data = d.data[n:]
+	return s
+}
+
+// done returns the error, if any, and makes sure nothing is left over.
+func (d *decoder) done() error {
+	if d.err == nil && len(d.data) > 0 {
+		d.fail()
+	}
+	return d.err
+}
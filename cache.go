@@ -0,0 +1,84 @@
+package delta
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+)
+
+// Cache compares revisions like Calculate, with options set once, and keeps
+// the most recently used diffs around, so rendering the same pair of
+// revisions again, as web interfaces keep doing, costs next to nothing.
+// Revisions are looked up by their SHA-256 hashes, so the cache doesn't hold
+// on to them. A Cache is safe for use by several goroutines at once.
+//
+// Diffs found in the cache aren't calculated again, so options such as
+// WithMetrics and WithTrace only hear of the ones which were.
+type Cache struct {
+	opts []Option
+	size int
+
+	mu      sync.Mutex
+	entries map[cacheKey]*list.Element
+	recent  *list.List
+}
+
+// cacheKey tells diffs apart by what they were calculated of.
+type cacheKey struct {
+	prev, curr [sha256.Size]byte
+	plaintext  bool
+}
+
+// cacheEntry is a diff kept in the cache.
+type cacheEntry struct {
+	key  cacheKey
+	diff string
+}
+
+// NewCache returns a Cache with the given options, keeping up to size diffs.
+func NewCache(size int, opts ...Option) *Cache {
+	return &Cache{
+		opts:    opts,
+		size:    size,
+		entries: make(map[cacheKey]*list.Element),
+		recent:  list.New(),
+	}
+}
+
+// Calculate is like the Calculate function, with the options of the Cache,
+// returning the diff from the cache when it's there.
+func (c *Cache) Calculate(prev, curr string, plaintext bool) string {
+	key := cacheKey{sha256.Sum256([]byte(prev)), sha256.Sum256([]byte(curr)), plaintext}
+
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		c.recent.MoveToFront(e)
+		c.mu.Unlock()
+		return e.Value.(*cacheEntry).diff
+	}
+	c.mu.Unlock()
+
+	// Calculating can take a while, so the cache isn't held up in the
+	// meantime. Two goroutines may calculate the same diff, in which
+	// case the first one is kept.
+	diff := Calculate(prev, curr, plaintext, c.opts...)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok && c.size > 0 {
+		c.entries[key] = c.recent.PushFront(&cacheEntry{key, diff})
+		if c.recent.Len() > c.size {
+			oldest := c.recent.Back()
+			c.recent.Remove(oldest)
+			delete(c.entries, oldest.Value.(*cacheEntry).key)
+		}
+	}
+	return diff
+}
+
+// Len returns how many diffs the cache holds.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.recent.Len()
+}
@@ -0,0 +1,33 @@
+package delta
+
+// Changed tells whether Calculate would show any changes between the two
+// revisions with the given options, without working them out, so callers
+// can skip rendering diffs which would show nothing. Identical revisions
+// are taken as unchanged right away, and otherwise the tokens are compared
+// by their keys until the first difference.
+//
+//	delta.Changed("a cafe", "a café", delta.WithFoldedDiacritics())
+//		// false
+func Changed(prev, curr string, opts ...Option) bool {
+	if prev == curr {
+		return false
+	}
+
+	o := newOptions(true, opts)
+	// These options hide some of the changes between the tokens, which
+	// only shows once they're rendered.
+	if o.reflow || o.ignoreBlank || o.hideComments {
+		return calculate(prev, curr, o) != calculate(curr, curr, newOptions(true, opts))
+	}
+
+	p, c := o.tokenize(prev), o.tokenize(curr)
+	if len(p) != len(c) {
+		return true
+	}
+	for i := range p {
+		if p[i] != c[i] && (!o.keyed() || o.key(p[i]) != o.key(c[i])) {
+			return true
+		}
+	}
+	return false
+}
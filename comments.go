@@ -0,0 +1,185 @@
+package delta
+
+import "strings"
+
+// Comments tells how comments are written in a programming language: the
+// markers starting comments which run to the end of the line, and the pairs
+// of markers around block comments.
+type Comments struct {
+	Line  []string
+	Block [][2]string
+}
+
+// Comment syntaxes of common languages.
+var (
+	CommentsC     = Comments{Line: []string{"//"}, Block: [][2]string{{"/*", "*/"}}}
+	CommentsShell = Comments{Line: []string{"#"}}
+	CommentsSQL   = Comments{Line: []string{"--"}, Block: [][2]string{{"/*", "*/"}}}
+	CommentsHTML  = Comments{Block: [][2]string{{"<!--", "-->"}}}
+)
+
+// WithComments marks changes made only to comments, written as given, with
+// class="comment", so they can be styled down and reviewers see the changes
+// to the code first. Rows of SideBySide holding only comments get the class
+// too. Markers inside string literals aren't told apart from real ones.
+func WithComments(c Comments) Option {
+	return func(o *options) {
+		o.comments = &c
+	}
+}
+
+// WithoutComments takes changes made only to comments, written as given,
+// as unchanged, showing the comments of the current revision. Unlike
+// WithComments it works for plain text too.
+func WithoutComments(c Comments) Option {
+	return func(o *options) {
+		o.comments = &c
+		o.hideComments = true
+	}
+}
+
+// scan goes over the text, which starts inside a comment ending in end, or
+// outside of comments if end is empty. A comment running to the end of the
+// line ends in "\n". It tells whether the text starts with a comment, and
+// where it leaves off.
+func (c *Comments) scan(text, end string) (bool, string) {
+	commented := end != ""
+	if !commented {
+		trimmed := strings.TrimLeft(text, " \t")
+		_, _, at := c.open(trimmed)
+		commented = at == 0
+	}
+
+	for len(text) > 0 {
+		switch end {
+		case "\n":
+			return commented, end
+		case "":
+			opener, closer, at := c.open(text)
+			if at < 0 {
+				return commented, end
+			}
+			text, end = text[at+len(opener):], closer
+		default:
+			at := strings.Index(text, end)
+			if at < 0 {
+				return commented, end
+			}
+			text, end = text[at+len(end):], ""
+		}
+	}
+	return commented, end
+}
+
+// open finds the first comment marker in the text, returning it along with
+// the marker closing it, and where it is. It returns -1 if there is none.
+func (c *Comments) open(text string) (string, string, int) {
+	opener, closer, first := "", "", -1
+	try := func(start, end string) {
+		if at := strings.Index(text, start); at >= 0 && (first < 0 || at < first) {
+			opener, closer, first = start, end, at
+		}
+	}
+	for _, m := range c.Line {
+		try(m, "\n")
+	}
+	for _, m := range c.Block {
+		try(m[0], m[1])
+	}
+	return opener, closer, first
+}
+
+// commentedTokens tells for every token whether it's part of a comment.
+func (o *options) commentedTokens(tokens []string) []bool {
+	flags := make([]bool, len(tokens))
+	end := ""
+	for i, w := range tokens {
+		if w == tokenDouble || w == tokenSingle {
+			if end == "\n" {
+				end = ""
+			}
+			continue
+		}
+		flags[i], end = o.comments.scan(w, end)
+	}
+	return flags
+}
+
+// commentedLines tells for every line whether it's only a comment.
+func (o *options) commentedLines(lines []string) []bool {
+	flags := make([]bool, len(lines))
+	end := ""
+	for i, l := range lines {
+		var commented bool
+		commented, end = o.comments.scan(l, end)
+		flags[i] = commented || (empty(l) && end != "" && end != "\n")
+		if end == "\n" {
+			end = ""
+		}
+	}
+	return flags
+}
+
+// annotate finds the changes which were made only to comments, if asked to.
+func (o *options) annotate(changes []Edit[string], prev, curr []string) {
+	if o.comments == nil {
+		return
+	}
+
+	if o.reflow {
+		prev, curr = sides(changes)
+	}
+	p, c := o.commentedTokens(prev), o.commentedTokens(curr)
+	o.commented = make([]bool, len(changes))
+	i, j := 0, 0
+	for n, ch := range changes {
+		switch ch.Op {
+		case Equal:
+			i, j = i+len(ch.Items), j+len(ch.Items)
+		case Delete:
+			o.commented[n] = confined(ch.Items, p[i:i+len(ch.Items)], o)
+			i += len(ch.Items)
+		case Insert:
+			o.commented[n] = confined(ch.Items, c[j:j+len(ch.Items)], o)
+			j += len(ch.Items)
+		}
+	}
+}
+
+// confined tells whether the words which aren't line breaks or whitespace
+// are all in comments, and there are some.
+func confined(words []string, commented []bool, o *options) bool {
+	some := false
+	for i, w := range words {
+		if w == "" || o.blank(w) {
+			continue
+		}
+		if !commented[i] {
+			return false
+		}
+		some = true
+	}
+	return some
+}
+
+// withoutComments marks the rows changing only comments, or takes them as
+// unchanged when comments are to be hidden.
+func withoutComments(rs []row, prev, curr []string, o *options) []row {
+	p, c := o.commentedLines(prev), o.commentedLines(curr)
+	kept := rs[:0]
+	for _, r := range rs {
+		commented := r.op != Equal &&
+			(r.prevLine == 0 || p[r.prevLine-1]) && (r.currLine == 0 || c[r.currLine-1])
+		switch {
+		case !commented:
+		case !o.hideComments:
+			r.comment = true
+		case r.currLine == 0:
+			continue
+		default:
+			r.op, r.paired = Equal, false
+		}
+		kept = append(kept, r)
+	}
+	return kept
+}
@@ -0,0 +1,10 @@
+package delta
+
+import "testing"
+
+func TestCommentsReflow(t *testing.T) {
+	got := Calculate("x = 1 // one\ny = 2", "x = 1\n// one\ny = 3", true, WithComments(CommentsC), WithReflow())
+	if want := "x = 1\n// one\ny = ---2--- +++3+++"; got != want {
+		t.Errorf("Calculate = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,151 @@
+package delta
+
+import (
+	"regexp"
+	"sort"
+)
+
+// regexpWord matches a single word, as split on whitespace.
+var regexpWord = regexp.MustCompile(`\S+`)
+
+// Passage is a run of consecutive words which two texts have in common. Prev
+// and Curr are the byte offsets it starts at in either text, and Words how
+// many words it's long. Text is the passage as it's written in prev.
+type Passage struct {
+	Text       string
+	Prev, Curr int
+	Words      int
+}
+
+// LongestCommon returns the longest passages the two texts have in common,
+// which tells more about text being reused than a diff does. There's more
+// than one when several are just as long, in the order they appear in prev.
+// Words are split on whitespace, and compared taking options normalizing
+// them into account.
+//
+//	delta.LongestCommon("the quick brown fox", "a quick brown dog")
+//		// []Passage{{Text: "quick brown", Prev: 4, Curr: 2, Words: 2}}
+func LongestCommon(prev, curr string, opts ...Option) []Passage {
+	o := newOptions(false, opts)
+	p, pw := o.words(prev)
+	c, cw := o.words(curr)
+
+	var best []Passage
+	longest := 0
+	diagonals(p, c, func(i, j, n int) {
+		if n < longest {
+			return
+		}
+		if n > longest {
+			best, longest = best[:0], n
+		}
+		best = append(best, passage(prev, curr, pw, cw, i, j, n))
+	})
+
+	sortPassages(best)
+	return best
+}
+
+// CommonPassages returns up to k of the longest passages the two texts have
+// in common, of at least min words each, longest first. Every word is part
+// of one passage at most, so passages overlapping a longer one, in either
+// text, are left out. A k of 0 or less returns all of them. Like
+// LongestCommon, words are split on whitespace.
+//
+//	delta.CommonPassages("a b c x d e", "d e y a b c", 2, 2)
+//		// []Passage{{Text: "a b c", Prev: 0, Curr: 6, Words: 3}, {Text: "d e", Prev: 8, Curr: 0, Words: 2}}
+func CommonPassages(prev, curr string, k, min int, opts ...Option) []Passage {
+	o := newOptions(false, opts)
+	p, pw := o.words(prev)
+	c, cw := o.words(curr)
+
+	type run struct{ i, j, n int }
+	var runs []run
+	diagonals(p, c, func(i, j, n int) {
+		if n >= max(min, 1) {
+			runs = append(runs, run{i, j, n})
+		}
+	})
+	sort.SliceStable(runs, func(a, b int) bool {
+		return runs[a].n > runs[b].n
+	})
+
+	usedP, usedC := make([]bool, len(p)), make([]bool, len(c))
+	var passages []Passage
+	for _, r := range runs {
+		if k > 0 && len(passages) == k {
+			break
+		}
+		if taken(usedP[r.i:r.i+r.n]) || taken(usedC[r.j:r.j+r.n]) {
+			continue
+		}
+		for x := 0; x < r.n; x++ {
+			usedP[r.i+x], usedC[r.j+x] = true, true
+		}
+		passages = append(passages, passage(prev, curr, pw, cw, r.i, r.j, r.n))
+	}
+	return passages
+}
+
+// taken tells whether any of the words is already part of a passage.
+func taken(used []bool) bool {
+	for _, u := range used {
+		if u {
+			return true
+		}
+	}
+	return false
+}
+
+// words splits the text into words along with their positions, and returns
+// the keys of the words.
+func (o *options) words(text string) ([]string, [][]int) {
+	positions := regexpWord.FindAllStringIndex(text, -1)
+	keys := make([]string, len(positions))
+	for i, m := range positions {
+		keys[i] = o.key(text[m[0]:m[1]])
+	}
+	return keys, positions
+}
+
+// diagonals calls found with every run of matching words which can't be made
+// any longer, given by where it starts in both slices and its length.
+func diagonals(prev, curr []string, found func(i, j, n int)) {
+	row := make([]int, len(curr)+1)
+	last := make([]int, len(curr)+1)
+	for i := range prev {
+		for j := range curr {
+			if prev[i] == curr[j] {
+				row[j+1] = last[j] + 1
+			} else {
+				row[j+1] = 0
+			}
+			// A run ends when the next pair of words doesn't match.
+			if n := row[j+1]; n > 0 && (i+1 == len(prev) || j+1 == len(curr) || prev[i+1] != curr[j+1]) {
+				found(i+1-n, j+1-n, n)
+			}
+		}
+		row, last = last, row
+	}
+}
+
+// passage makes the passage of n words starting at the i-th word of prev and
+// the j-th of curr.
+func passage(prev, curr string, pw, cw [][]int, i, j, n int) Passage {
+	return Passage{
+		Text:  prev[pw[i][0]:pw[i+n-1][1]],
+		Prev:  pw[i][0],
+		Curr:  cw[j][0],
+		Words: n,
+	}
+}
+
+// sortPassages orders passages by where they are in prev, then in curr.
+func sortPassages(ps []Passage) {
+	sort.Slice(ps, func(i, j int) bool {
+		if ps[i].Prev != ps[j].Prev {
+			return ps[i].Prev < ps[j].Prev
+		}
+		return ps[i].Curr < ps[j].Curr
+	})
+}
@@ -5,65 +5,337 @@
 //
 // Examples:
 //
-//  delta.Calculate("hello world", "hello earth", false)
+//	delta.Calculate("hello world", "hello earth", false)
 //		// "hello <del>world</del> <ins>earth</ins>"
 //
 //	delta.Calculate("hello world", "hello earth", true)
 //		// "hello ---world--- +++earth+++"
+//
+// The output only depends on the inputs and the options, so the same inputs
+// give byte-identical output on every platform and, as far as this package
+// is concerned, in every version. When several diffs are equally short, the
+// one picked follows the rules documented on DiffSlices.
 package delta
 
 import (
+	"bytes"
+	"fmt"
 	"html"
+	"io"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 var (
 	regexpNewline = regexp.MustCompile(`\r\n?`)
-	regexpDouble = regexp.MustCompile(`(\s*?)&__DOUBLE__;(\s*)`)
-	regexpSingle = regexp.MustCompile(`(\s*?)&__SINGLE__;(\s*)`)
+	regexpDouble  = regexp.MustCompile(`([^\S\t]*?)&__DOUBLE__;([^\S\t]*)`)
+	regexpSingle  = regexp.MustCompile(`([^\S\t]*?)&__SINGLE__;([^\S\t]*)`)
+)
+
+// Tokens standing in for line breaks. They can never show up as words, since
+// splitWords splits every newline out of the text.
+const (
+	tokenDouble = "\n\n"
+	tokenSingle = "\n"
 )
 
 // Calculate accepts the two revisions of text, first one being the previous
 // (older) and second being the current (newer) version. It returns the string
-// representation of the diff, in either HTML or plain text.
-func Calculate(prev, curr string, plaintext bool) string {
-	p, c := preprocess(prev), preprocess(curr)
+// representation of the diff, in either HTML or plain text. Options tune the
+// output further; without any, the result is the same as it always was.
+func Calculate(prev, curr string, plaintext bool, opts ...Option) string {
+	return calculate(prev, curr, newOptions(plaintext, opts))
+}
 
-	return postprocess(print(sequence(p, c), p, c, len(p)-1, len(c)-1), plaintext)
+// calculate does the work of Calculate with the given options.
+func calculate(prev, curr string, o *options) string {
+	var b strings.Builder
+	b.Grow(o.grow)
+	calculateTo(&b, prev, curr, o)
+	return b.String()
 }
 
-// sequence builds the necessary matrix and computes the length of it. It
-// also reads through the matrix and computes the longest common subsequence.
-func sequence(prev, curr []string) map[int]map[int]int {
-	c := make(map[int]map[int]int)
+// calculateTo does the work of Calculate with the given options, writing
+// the diff to w as it's rendered.
+func calculateTo(w io.Writer, prev, curr string, o *options) (int64, error) {
+	if o.metrics != nil {
+		defer o.report(time.Now(), len(prev), len(curr))
+	}
+	o.language(prev, curr)
+	o.granularity(prev, curr)
+	p, c := o.tokenize(prev), o.tokenize(curr)
+	return o.write(w, o.diff(p, c), p, c)
+}
 
-	for i := -1; i <= len(prev); i++ {
-		// For some reason, when making multidimensional matrices, Go
-		// only makes the outermost one, and all deeper ones need to
-		// be created manually. An afternoon well spent.
-		_, ok := c[i]
-		if !ok {
-			c[i] = make(map[int]int)
-		}
+// AppendCalculate is like Calculate, but appends the diff to dst and returns
+// the extended buffer, so services rendering many diffs can write them into
+// buffers they reuse. The diff is rendered straight into dst, which only
+// grows when it runs out of room.
+func AppendCalculate(dst []byte, prev, curr string, plaintext bool, opts ...Option) []byte {
+	b := bytes.NewBuffer(dst)
+	calculateTo(b, prev, curr, newOptions(plaintext, opts))
+	return b.Bytes()
+}
+
+// CalculateTo is like Calculate, but writes the diff to w, such as an HTTP
+// response or a file, as it's rendered, and returns how many bytes were
+// written along with any error writing them. Filters set by WithFilters
+// need all of the diff at once, so with them it's only written out once
+// it's done.
+func CalculateTo(w io.Writer, prev, curr string, plaintext bool, opts ...Option) (int64, error) {
+	return calculateTo(w, prev, curr, newOptions(plaintext, opts))
+}
+
+// Output is a diff which is only rendered as it's written out, as returned
+// by Render. It implements io.WriterTo, so it can be handed to anything
+// copying from one, like io.Copy.
+type Output struct {
+	prev, curr string
+	plaintext  bool
+	opts       []Option
+}
+
+// Render is like Calculate, but leaves rendering the diff to the Output it
+// returns, for large diffs to go straight into HTTP responses or files
+// without being built as one string first.
+//
+//	delta.Render(prev, curr, false).WriteTo(w)
+func Render(prev, curr string, plaintext bool, opts ...Option) Output {
+	return Output{prev, curr, plaintext, opts}
+}
+
+// WriteTo renders the diff to w like CalculateTo.
+func (d Output) WriteTo(w io.Writer) (int64, error) {
+	return CalculateTo(w, d.prev, d.curr, d.plaintext, d.opts...)
+}
+
+// String renders the diff like Calculate.
+func (d Output) String() string {
+	return Calculate(d.prev, d.curr, d.plaintext, d.opts...)
+}
+
+// CalculateTokens is like Calculate, but for text which has already been
+// split into tokens, such as sentences or CSV fields. The tokens are compared
+// as they are and joined with spaces in the output. A token of "\n" or "\n\n"
+// stands for a line break or the end of a paragraph respectively.
+// WithTokenizer is ignored, since the text is already split.
+func CalculateTokens(prev, curr []string, plaintext bool, opts ...Option) string {
+	return calculateTokens(prev, curr, newOptions(plaintext, opts))
+}
+
+// calculateTokens does the work of CalculateTokens with the given options.
+func calculateTokens(prev, curr []string, o *options) string {
+	if o.tokenizer != nil {
+		o.warn("delta: WithTokenizer ignored, the text is already split")
+	}
+	o.tokenizer = nil
+	if o.metrics != nil {
+		defer o.report(time.Now(), size(prev), size(curr))
+	}
+	return o.output(o.diff(prev, curr), prev, curr)
+}
+
+// CalculateValues is like Calculate, but for values which may be missing on
+// either side, such as the fields of a record or the headers of a message.
+// An empty value has no words at all, rather than a single empty one, so a
+// value which was added or removed shows as nothing but an insertion or a
+// deletion.
+//
+//	delta.CalculateValues("", "hello world", true)
+//		// "+++hello world+++"
+func CalculateValues(prev, curr string, plaintext bool, opts ...Option) string {
+	o := newOptions(plaintext, opts)
+	if prev != "" && curr != "" {
+		return calculate(prev, curr, o)
+	}
+	if o.metrics != nil {
+		defer o.report(time.Now(), len(prev), len(curr))
+	}
+	p, c := o.value(prev), o.value(curr)
+	return o.output(o.diff(p, c), p, c)
+}
+
+// value splits a value into tokens, of which an empty value has none.
+func (o *options) value(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return o.tokenize(text)
+}
+
+// output renders the changes between the tokens as asked to.
+func (o *options) output(changes []Edit[string], prev, curr []string) string {
+	var b strings.Builder
+	b.Grow(o.grow)
+	o.write(&b, changes, prev, curr)
+	return b.String()
+}
+
+// write renders the changes between the tokens like output, writing them to
+// w as they're rendered.
+func (o *options) write(w io.Writer, changes []Edit[string], prev, curr []string) (int64, error) {
+	if summary := o.replacement(changes); summary != "" {
+		n, err := io.WriteString(w, summary)
+		return int64(n), err
+	}
+	changes = o.arrange(changes)
+	o.annotate(changes, prev, curr)
+	o.classify(changes)
+	if o.metrics != nil {
+		o.measured.Readability = Readability(changes)
+		o.measured.Weight = o.changed(changes)
+	}
+
+	f := postprocess(w, o)
+	render(f, changes, o)
+	return f.Close()
+}
+
+// rendered renders the changes and runs them through postprocess, for parts
+// of the output made of several diffs.
+func rendered(changes []Edit[string], o *options) string {
+	var b strings.Builder
+	f := postprocess(&b, o)
+	render(f, changes, o)
+	f.Close()
+	return b.String()
+}
+
+// Operation tells what happened to a run of items between the revisions.
+type Operation int
+
+const (
+	Equal Operation = iota
+	Insert
+	Delete
+)
+
+// String returns the name of the operation.
+func (op Operation) String() string {
+	switch op {
+	case Equal:
+		return "equal"
+	case Insert:
+		return "insert"
+	case Delete:
+		return "delete"
+	}
+	return "Operation(" + strconv.Itoa(int(op)) + ")"
+}
+
+// MarshalText encodes the operation as its name, so it reads as such in JSON
+// and other text formats.
+func (op Operation) MarshalText() ([]byte, error) {
+	switch op {
+	case Equal, Insert, Delete:
+		return []byte(op.String()), nil
+	}
+	return nil, fmt.Errorf("delta: unknown operation %d", int(op))
+}
 
-		for j := -1; j <= len(curr); j++ {
-			c[i][j] = 1
+// UnmarshalText decodes the operation from its name.
+func (op *Operation) UnmarshalText(text []byte) error {
+	for _, o := range []Operation{Equal, Insert, Delete} {
+		if string(text) == o.String() {
+			*op = o
+			return nil
 		}
 	}
+	return fmt.Errorf("delta: unknown operation %q", text)
+}
+
+// Edit is a run of consecutive items which were all kept, inserted or
+// deleted. A list of edits, in order, turns the previous revision into the
+// current one.
+type Edit[T any] struct {
+	Op    Operation `json:"op"`
+	Items []T       `json:"items"`
+}
+
+// String returns the operation followed by the items, as in "insert [a b]".
+func (e Edit[T]) String() string {
+	return fmt.Sprintf("%s %v", e.Op, e.Items)
+}
+
+// DiffSlices compares two slices of anything comparable, such as ints,
+// structs or parsed tokens, using the same algorithm as Calculate. It returns
+// the edits turning a into b, with adjacent items sharing the same operation
+// grouped together. Kept items are the ones from a.
+//
+// Of all the shortest ways of turning a into b, the one returned is found by
+// walking back from the ends of both slices: the last items left are kept
+// when they're equal, otherwise the last item of b is taken as inserted,
+// unless that would leave fewer items to keep, in which case the last item
+// of a is taken as deleted. In practice, deletions come before the
+// insertions replacing them, and an item repeated in b is matched with its
+// last occurrence, so DiffSlices([]int{1}, []int{1, 1}) inserts the first 1.
+// Calculate and the rest of the package follow the same rules.
+//
+//	delta.DiffSlices([]int{1, 2, 3}, []int{1, 3, 4})
+//		// []Edit[int]{{Equal, []int{1}}, {Delete, []int{2}}, {Equal, []int{3}}, {Insert, []int{4}}}
+func DiffSlices[T comparable](a, b []T) []Edit[T] {
+	c := sequence(newMatrix(len(a), len(b)), a, b)
+	defer c.release()
+	return backtrack(c, a, b)
+}
+
+// matrix holds the lengths of the longest common subsequences of all the
+// prefixes of the two sequences. It has an extra row and column in front for
+// the empty prefixes, which is where the -1 indices end up.
+type matrix struct {
+	cols  int
+	cells []int32
+}
+
+// at returns the length for the prefixes ending at prev[i] and curr[j].
+func (m matrix) at(i, j int) int32 {
+	return m.cells[(i+1)*m.cols+j+1]
+}
+
+// cells keeps the slices of released matrices, since they take up most of
+// the memory a diff needs.
+var cells sync.Pool
+
+// newMatrix returns a matrix of zeros for sequences of the given lengths,
+// reusing the slice of a released one if it's large enough.
+func newMatrix(rows, cols int) matrix {
+	n := (rows + 1) * (cols + 1)
+	if p, ok := cells.Get().(*[]int32); ok && cap(*p) >= n {
+		c := (*p)[:n]
+		clear(c)
+		return matrix{cols: cols + 1, cells: c}
+	}
+	return matrix{cols: cols + 1, cells: make([]int32, n)}
+}
+
+// release hands the slice of the matrix back for reuse, unless it's so
+// large that holding on to it would cost more than making it again. The
+// matrix can't be used afterwards.
+func (m matrix) release() {
+	if len(m.cells) <= largeMatrix {
+		cells.Put(&m.cells)
+	}
+}
+
+// sequence fills in the matrix, which has to be made of zeros for sequences
+// of the lengths of prev and curr, with the lengths of the longest common
+// subsequences.
+func sequence[T comparable](c matrix, prev, curr []T) matrix {
+	// Making a map of maps for every cell used to take most of the
+	// time, so the whole matrix now lives in a single slice.
 
 	for i := 0; i <= len(prev)-1; i++ {
+		row, above := c.cells[(i+1)*c.cols:], c.cells[i*c.cols:]
 		for j := 0; j <= len(curr)-1; j++ {
 			if prev[i] == curr[j] {
-				c[i][j] = c[i-1][j-1] + 1
+				row[j+1] = above[j] + 1
 			} else {
-				var max int
-				if c[i][j-1] > c[i-1][j] {
-					max = c[i][j-1]
-				} else {
-					max = c[i-1][j]
-				}
-				c[i][j] = max
+				row[j+1] = max(row[j], above[j+1])
 			}
 		}
 	}
@@ -71,57 +343,504 @@ func sequence(prev, curr []string) map[int]map[int]int {
 	return c
 }
 
-// print backtracks over the input sequences and prints out the calculated
-// differences between them. The output is HTML which is later processed and
-// cleaned up.
-func print(c map[int]map[int]int, prev, curr []string, i, j int) string {
-	var output string
+// backtrack walks back over the matrix and collects the differences between
+// the input sequences, grouping adjacent items with the same operation. Ties
+// are broken as documented on DiffSlices, and that has to stay so, since
+// callers rely on the output being stable.
+func backtrack[T comparable](c matrix, prev, curr []T) []Edit[T] {
+	var edits []Edit[T]
+
+	add := func(op Operation, item T) {
+		if n := len(edits); n > 0 && edits[n-1].Op == op {
+			edits[n-1].Items = append(edits[n-1].Items, item)
+			return
+		}
+		edits = append(edits, Edit[T]{op, []T{item}})
+	}
 
-	if i >= 0 && j >= 0 && prev[i] == curr[j] {
-		output += print(c, prev, curr, i-1, j-1)
-		output += string(prev[i]) + " "
+	// The walk goes from the end, so items are collected in reverse and
+	// put back in order once we're done.
+	for i, j := len(prev)-1, len(curr)-1; i >= 0 || j >= 0; {
+		if i >= 0 && j >= 0 && prev[i] == curr[j] {
+			add(Equal, prev[i])
+			i, j = i-1, j-1
+		} else if j >= 0 && (i == -1 || c.at(i, j-1) >= c.at(i-1, j)) {
+			// Insertions win ties, and end up after the
+			// deletions once the edits are put back in order.
+			add(Insert, curr[j])
+			j--
+		} else {
+			add(Delete, prev[i])
+			i--
+		}
+	}
+
+	for l, r := 0, len(edits)-1; l < r; l, r = l+1, r-1 {
+		edits[l], edits[r] = edits[r], edits[l]
+	}
+	for _, e := range edits {
+		for l, r := 0, len(e.Items)-1; l < r; l, r = l+1, r-1 {
+			e.Items[l], e.Items[r] = e.Items[r], e.Items[l]
+		}
+	}
+
+	return edits
+}
+
+// lcs diffs the sequences, statement by statement for WithSQL, or around
+// the anchors if there are any, and counts the edits towards the metrics
+// when asked to.
+func (o *options) lcs(prev, curr []string) []Edit[string] {
+	var edits []Edit[string]
+	switch {
+	case o.statements:
+		edits = o.byStatements(prev, curr)
+	case o.anchor != nil:
+		edits = o.anchored(prev, curr)
+	default:
+		edits = o.align(prev, curr)
+	}
+	o.count(prev, curr, edits)
+	return edits
+}
+
+// align diffs the sequences, tracing the steps when asked to.
+func (o *options) align(prev, curr []string) []Edit[string] {
+	n := (len(prev) + 1) * (len(curr) + 1)
+	if o.fallback > 0 && n > o.fallback {
+		return o.cheaper(prev, curr)
+	}
+	if n > largeMatrix {
+		o.warn("delta: comparing large inputs", "prev", len(prev), "curr", len(curr), "cells", n)
+	}
+	c := o.matrix(len(prev), len(curr))
+	if o.weighted {
+		c = weighted(c, prev, curr, weigh(prev, curr))
 	} else {
-		if j >= 0 && (i == -1 || c[i][j-1] >= c[i-1][j]) {
-			output += print(c, prev, curr, i, j-1)
-			output += "<ins>" + string(curr[j]) + "</ins> "
-		} else if i >= 0 && (j == -1 || c[i][j-1] < c[i-1][j]) {
-			output += print(c, prev, curr, i-1, j)
-			output += "<del>" + string(prev[i]) + "</del> "
+		c = sequence(c, prev, curr)
+	}
+	if o.trace != nil {
+		traceMatrix(o.trace, c, prev, curr)
+	}
+	edits := backtrack(c, prev, curr)
+	o.release(c)
+	if o.trace != nil {
+		tracePath(o.trace, edits)
+	}
+	return edits
+}
+
+// render prints out the changes as HTML to b, which is usually the filter
+// of postprocess cleaning it up. Line breaks are left as placeholders for
+// the filter.
+func render(b io.StringWriter, changes []Edit[string], o *options) {
+	corrected := -1
+	for n, ch := range changes {
+		if n == corrected {
+			continue
+		}
+		switch ch.Op {
+		case Equal:
+			if o.unchanged == "" || o.plaintext {
+				collapse(b, ch.Items, n > 0, n < len(changes)-1, o)
+				continue
+			}
+			var same strings.Builder
+			collapse(&same, ch.Items, n > 0, n < len(changes)-1, o)
+			b.WriteString(o.unchanged + strings.TrimSuffix(same.String(), o.space()) + "</" + o.unchangedTag + ">" + o.space())
+		case Insert, Delete:
+			// Line breaks and comments coming and going are taken
+			// as they are in the current revision, when asked to.
+			commented := o.commented != nil && o.commented[n]
+			if o.ignoreBlank && breaksOnly(ch.Items, o) || o.hideComments && commented {
+				if ch.Op == Insert {
+					for _, w := range ch.Items {
+						b.WriteString(word(w, o) + o.space())
+					}
+				}
+				continue
+			}
+
+			tag, del, _ := o.tags()
+			if ch.Op == Delete {
+				tag = del
+			}
+			author := o.author
+			if o.authors != nil {
+				author = o.authors[n]
+			}
+			var class []string
+			if commented {
+				class = append(class, "comment")
+			}
+			if o.kinds != nil && o.kinds[n] != "" {
+				class = append(class, o.kinds[n])
+			}
+
+			// Corrections are the deletion and the insertion
+			// next to it shown as one.
+			if old, new, ok := o.correction(changes, n); ok {
+				b.WriteString(o.correct(old, new, o.kinds[n], class, author))
+				corrected = n + 1
+				continue
+			}
+
+			// Changes spanning more lines are normally wrapped as
+			// a whole, but can be closed at the end of every line
+			// and opened again on the next one instead.
+			segments := [][]string{ch.Items}
+			if o.perLine {
+				segments = split(ch.Items)
+			}
+
+			for _, segment := range segments {
+				if o.perLine && (segment[0] == tokenDouble || segment[0] == tokenSingle) {
+					b.WriteString(word(segment[0], o) + o.space())
+					continue
+				}
+
+				b.WriteString(open(tag, ch.Op, replaced(changes, n), class, author, o))
+				if o.bidi && o.plaintext {
+					b.WriteString(markIsolate)
+				}
+				for i, w := range segment {
+					if i > 0 {
+						b.WriteString(o.space())
+					}
+					b.WriteString(word(w, o))
+				}
+				if o.bidi && o.plaintext {
+					b.WriteString(markPop)
+				}
+				b.WriteString("</" + tag + ">" + o.signature(author) + o.space())
+			}
 		}
 	}
+}
+
+// collapse writes out a run of unchanged words. Long runs are folded into a
+// details element when asked to, keeping a bit of context visible next to
+// the changes before and after.
+func collapse(b io.StringWriter, words []string, before, after bool, o *options) {
+	head, tail := 0, len(words)
+	if before {
+		head = context(words, 0, o.context, 1, o)
+	}
+	if after {
+		tail = context(words, len(words)-1, o.context, -1, o)
+	}
 
-	return output
+	hidden := 0
+	for _, w := range words[head:max(head, tail)] {
+		if !o.blank(w) {
+			hidden++
+		}
+	}
+	if o.plaintext || o.collapse == 0 || hidden <= o.collapse {
+		for _, w := range words {
+			b.WriteString(word(w, o) + o.space())
+		}
+		return
+	}
+
+	for _, w := range words[:head] {
+		b.WriteString(word(w, o) + o.space())
+	}
+	b.WriteString("<details><summary>" + html.EscapeString(fmt.Sprintf(o.summary, hidden)) + "</summary>")
+	for _, w := range words[head:tail] {
+		b.WriteString(word(w, o) + o.space())
+	}
+	b.WriteString("</details>" + o.space())
+	for _, w := range words[tail:] {
+		b.WriteString(word(w, o) + o.space())
+	}
 }
 
-// preprocess normalizes new lines, escapes the input, and replacesv new
-// lines so that changes that span across more lines get caught as such
-// and treated accordingly.
-func preprocess(input string) []string {
-	input = regexpNewline.ReplaceAllString(input, "\n")
-	input = strings.TrimSpace(html.EscapeString(input))
-	input = strings.Replace(input, "\n\n", " &__DOUBLE__; ", -1)
-	input = strings.Replace(input, "\n", " &__SINGLE__; ", -1)
-	return strings.Split(input, " ")
+// context finds where n words of context end, counting from the given index
+// in the given direction. Line breaks don't count as words.
+func context(words []string, from, n, dir int, o *options) int {
+	i := from
+	for ; i >= 0 && i < len(words) && n > 0; i += dir {
+		if !o.blank(words[i]) {
+			n--
+		}
+	}
+	if dir < 0 {
+		return i + 1
+	}
+	return i
 }
 
-// postprocess finalizes the output by merging adjacent HTML tags, returning
-// the previously removed new lines, and converting between HTML and text.
-func postprocess(input string, plaintext bool) string {
-	input = strings.Replace(input, "</del> <del>", " ", -1)
-	input = strings.Replace(input, "</ins> <ins>", " ", -1)
+// open returns the opening tag for a change. Plain text only ever gets the
+// bare tag, since postprocess has to find and replace it.
+func open(tag string, op Operation, replaced []string, class []string, author string, o *options) string {
+	if o.plaintext {
+		return "<" + tag + ">"
+	}
+
+	var b strings.Builder
+	b.WriteString("<" + tag)
+
+	role, label := "insertion", o.insertedLabel
+	if op == Delete {
+		role, label = "deletion", o.deletedLabel
+	}
+	if o.accessible {
+		b.WriteString(` role="` + role + `"`)
+	}
+	if o.bidi {
+		b.WriteString(` dir="auto"`)
+	}
+	if class != nil {
+		b.WriteString(` class="` + strings.Join(class, " ") + `"`)
+	}
+	if author != "" {
+		b.WriteString(` data-author="` + html.EscapeString(author) + `"`)
+	}
+	for _, attr := range o.metadata {
+		b.WriteString(" " + attr.name + `="` + html.EscapeString(attr.value) + `"`)
+	}
+	if o.tooltip != "" && replaced != nil {
+		b.WriteString(" " + o.tooltip + `="` + html.EscapeString(o.mask(o.join(replaced))) + `"`)
+	}
+	b.WriteString(">")
+
+	if o.accessible {
+		b.WriteString(`<span class="` + html.EscapeString(o.hiddenClass) + `">` + html.EscapeString(label) + "</span>")
+	}
+	return b.String()
+}
+
+// breaksOnly tells whether the words are all line breaks or whitespace.
+func breaksOnly(words []string, o *options) bool {
+	for _, w := range words {
+		if w != "" && !o.blank(w) {
+			return false
+		}
+	}
+	return true
+}
+
+// split cuts the words at line breaks, which end up in segments of their own.
+func split(words []string) [][]string {
+	var segments [][]string
+	start := 0
+	for i, w := range words {
+		if w == tokenDouble || w == tokenSingle {
+			if i > start {
+				segments = append(segments, words[start:i])
+			}
+			segments = append(segments, words[i:i+1])
+			start = i + 1
+		}
+	}
+	if start < len(words) {
+		segments = append(segments, words[start:])
+	}
+	return segments
+}
 
+// replaced returns the words an insertion took the place of, that is the
+// deletion right next to it, or nil if it is a plain insertion.
+func replaced(changes []Edit[string], n int) []string {
+	if changes[n].Op != Insert {
+		return nil
+	}
+	if n > 0 && changes[n-1].Op == Delete {
+		return changes[n-1].Items
+	}
+	if n < len(changes)-1 && changes[n+1].Op == Delete {
+		return changes[n+1].Items
+	}
+	return nil
+}
+
+// join puts the words back together into plain, unescaped text.
+func (o *options) join(words []string) string {
+	if o.tokenizer != nil {
+		return strings.Join(words, "")
+	}
+
+	var b strings.Builder
+	for i, w := range words {
+		if i > 0 && w != tokenDouble && w != tokenSingle && words[i-1] != tokenDouble && words[i-1] != tokenSingle {
+			b.WriteString(" ")
+		}
+		b.WriteString(w)
+	}
+	return b.String()
+}
+
+// word escapes a single word for the output, swapping line breaks for the
+// placeholders postprocess knows how to restore.
+func word(w string, o *options) string {
+	switch w {
+	case tokenDouble:
+		return "&__DOUBLE__;"
+	case tokenSingle:
+		return "&__SINGLE__;"
+	}
+	return o.escape(o.mask(w))
+}
+
+// splitWords splits the text into words, and splits new lines out of them
+// so that changes that span across more lines get caught as such and
+// treated accordingly.
+func splitWords(input string) []string {
+	var words []string
+	for i, paragraph := range strings.Split(input, "\n\n") {
+		if i > 0 {
+			words = append(words, tokenDouble)
+		}
+		for j, line := range strings.Split(paragraph, "\n") {
+			if j > 0 {
+				words = append(words, tokenSingle)
+			}
+			words = append(words, strings.Split(line, " ")...)
+		}
+	}
+	return words
+}
+
+// postprocess returns the filter finalizing the output on its way to w,
+// which returns the previously removed new lines and converts between HTML
+// and text.
+func postprocess(w io.Writer, o *options) *filter {
 	// Plain text is different than HTML in way that HTML variant
 	// uses the <ins> and <del> tags, while plain text variant
 	// uses three + and - characters to wrap added and removed
-	// pieces of text.
-	if plaintext {
-		input = strings.Replace(input, "<ins>", "+++", -1)
-		input = strings.Replace(input, "</ins>", "+++", -1)
-		input = strings.Replace(input, "<del>", "---", -1)
-		input = strings.Replace(input, "</del>", "---", -1)
+	// pieces of text. Terminals get colors instead.
+	var filters []Stage
+	if o.plaintext && o.theme != nil {
+		filters = append(filters, Stage{"markers", strings.NewReplacer(
+			"<ins>", o.theme.Insert.start(), "</ins>", o.theme.Insert.end(),
+			"<del>", o.theme.Delete.start(), "</del>", o.theme.Delete.end(),
+		).Replace})
+	} else if o.plaintext {
+		filters = append(filters, Stage{"markers", strings.NewReplacer(
+			"<ins>", "+++", "</ins>", "+++",
+			"<del>", "---", "</del>", "---",
+		).Replace})
+	}
+
+	filters = append(filters, Stage{"newlines", func(input string) string {
+		input = regexpDouble.ReplaceAllString(input, "\n\n")
+		return regexpSingle.ReplaceAllString(input, "\n")
+	}})
+
+	// Escaped entities are no good in a terminal, they are meant to
+	// read the text as it is.
+	if o.plaintext && o.theme != nil {
+		filters = append(filters, Stage{"unescape", html.UnescapeString})
+	}
+
+	// Filters of the caller's own may work on any part of the output, so
+	// they get all of it at once.
+	if o.filters != nil {
+		filters = o.filters(append(filters, Stage{"trim", trim}))
+		return &filter{w: w, filters: filters, whole: true}
 	}
+	return &filter{w: w, filters: filters}
+}
+
+// filterChunk is how much output a filter holds on to before letting some
+// of it through.
+const filterChunk = 4096
+
+// filter runs the output through the filters of postprocess on its way to
+// w. The output is let through in chunks cut between words, where none of
+// the filters of postprocess can tell the difference, holding back what's
+// after the last cut until more is written. Only the ends of the whole
+// output are trimmed. With filters set by WithFilters, all of the output is
+// held back until Close.
+type filter struct {
+	w       io.Writer
+	filters []Stage
+	whole   bool
+	buf     []byte
+	started bool
+	n       int64
+	err     error
+}
+
+// WriteString adds s to the output.
+func (f *filter) WriteString(s string) (int, error) {
+	f.buf = append(f.buf, s...)
+	if !f.whole && len(f.buf) >= filterChunk {
+		if at := cut(f.buf); at > 0 {
+			f.pass(string(f.buf[:at]), false)
+			f.buf = f.buf[:copy(f.buf, f.buf[at:])]
+		}
+	}
+	return len(s), f.err
+}
+
+// Close lets the rest of the output through, and returns how many bytes
+// were written to w along with any error writing them.
+func (f *filter) Close() (int64, error) {
+	f.pass(string(f.buf), true)
+	f.buf = nil
+	return f.n, f.err
+}
+
+// pass runs a chunk of the output through the filters and writes it out.
+func (f *filter) pass(text string, last bool) {
+	for _, s := range f.filters {
+		text = s.Apply(text)
+	}
+	if !f.whole {
+		if !f.started {
+			text = strings.TrimLeftFunc(text, trimmable)
+		}
+		if last {
+			text = strings.TrimRightFunc(text, trimmable)
+		}
+	}
+	if text == "" || f.err != nil {
+		return
+	}
+	f.started = true
+	n, err := io.WriteString(f.w, text)
+	f.n += int64(n)
+	f.err = err
+}
+
+// cut finds the last place the output can be cut at: the start of a word
+// which is followed by whitespace, so it's complete, and which is preceded
+// by another word. Neither of them may have an ampersand in them, since the
+// filters turn placeholders, and the whitespace around them, into line
+// breaks, and entities may turn into whitespace. It returns 0 if there's no
+// such place.
+func cut(b []byte) int {
+	at := 0
+	start, prev := -1, -1
+	amp, prevAmp := false, false
+	for i := 0; i < len(b); {
+		r, size := utf8.DecodeRune(b[i:])
+		switch {
+		case !unicode.IsSpace(r):
+			if start < 0 {
+				start, amp = i, false
+			}
+			amp = amp || r == '&'
+		case start >= 0:
+			if prev >= 0 && !prevAmp && !amp {
+				at = start
+			}
+			prev, prevAmp, start = start, amp, -1
+		}
+		i += size
+	}
+	return at
+}
+
+// trim cuts the whitespace off both ends of the text, except for tabs, which
+// are usually there on purpose as indentation.
+func trim(text string) string {
+	return strings.TrimFunc(text, trimmable)
+}
 
-	input = regexpDouble.ReplaceAllString(input, "\n\n")
-	input = regexpSingle.ReplaceAllString(input, "\n")
-	return strings.TrimSpace(input)
+// trimmable tells whether trim cuts the character off.
+func trimmable(r rune) bool {
+	return r != '\t' && unicode.IsSpace(r)
 }
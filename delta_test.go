@@ -0,0 +1,94 @@
+package delta
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAppendCalculate(t *testing.T) {
+	dst := make([]byte, 0, 64)
+	dst = append(dst, "diff: "...)
+	got := AppendCalculate(dst, "hello world", "hello earth", true)
+	if want := "diff: hello ---world--- +++earth+++"; string(got) != want {
+		t.Errorf("AppendCalculate = %q, want %q", got, want)
+	}
+	if &got[0] != &dst[:1][0] {
+		t.Error("AppendCalculate didn't render into dst")
+	}
+}
+
+func TestCalculateChunks(t *testing.T) {
+	// Long enough to be filtered in several chunks, which has to come
+	// out the same as filtering all of it at once.
+	prev := strings.Repeat("a &amp; b\n\n  c\td ", 1000)
+	curr := strings.Repeat("a &amp; c\n\n  c\td ", 1000)
+	whole := WithFilters(func(filters []Stage) []Stage { return filters })
+	for _, plaintext := range []bool{false, true} {
+		got := Calculate(prev, curr, plaintext)
+		want := Calculate(prev, curr, plaintext, whole)
+		if got != want {
+			t.Errorf("Calculate(plaintext %v) differs when filtered in chunks", plaintext)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	var b strings.Builder
+	n, err := Render("hello world", "hello earth", false).WriteTo(&b)
+	want := "hello <del>world</del> <ins>earth</ins>"
+	if b.String() != want || n != int64(len(want)) || err != nil {
+		t.Errorf("WriteTo = %q, %d, %v, want %q", b.String(), n, err, want)
+	}
+}
+
+func TestTies(t *testing.T) {
+	tests := []struct {
+		prev, curr string
+		want       string
+	}{
+		{"a b", "b a", "---a--- b +++a+++"},
+		{"a", "a a", "+++a+++ a"},
+		{"a b a", "a", "---a b--- a"},
+		{"x y", "y x y", "+++y+++ x y"},
+	}
+	for _, tt := range tests {
+		if got := Calculate(tt.prev, tt.curr, true); got != tt.want {
+			t.Errorf("Calculate(%q, %q) = %q, want %q", tt.prev, tt.curr, got, tt.want)
+		}
+	}
+
+	got := DiffSlices([]int{1}, []int{1, 1})
+	if len(got) != 2 || got[0].Op != Insert || got[1].Op != Equal {
+		t.Errorf("DiffSlices([1], [1 1]) = %v, want the first 1 inserted", got)
+	}
+}
+
+func TestMetadataCollisions(t *testing.T) {
+	metadata := map[string]string{"Revision ID": "2", "revision_id": "1", "revision-id": "3"}
+	want := Calculate("a", "b", false, WithMetadata(metadata))
+	for range 20 {
+		if got := Calculate("a", "b", false, WithMetadata(metadata)); got != want {
+			t.Fatalf("Calculate = %q, then %q", want, got)
+		}
+	}
+	if !strings.Contains(want, `data-revision-id="1" data-revision-id="2" data-revision-id="3"`) {
+		t.Errorf("Calculate = %q, want the colliding attributes in the order of their values", want)
+	}
+}
+
+func TestCalculateValues(t *testing.T) {
+	tests := []struct {
+		prev, curr string
+		want       string
+	}{
+		{"", "hello world", "+++hello world+++"},
+		{"hello world", "", "---hello world---"},
+		{"", "", ""},
+		{"hello world", "hello earth", "hello ---world--- +++earth+++"},
+	}
+	for _, tt := range tests {
+		if got := CalculateValues(tt.prev, tt.curr, true); got != tt.want {
+			t.Errorf("CalculateValues(%q, %q) = %q, want %q", tt.prev, tt.curr, got, tt.want)
+		}
+	}
+}
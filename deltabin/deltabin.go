@@ -0,0 +1,283 @@
+// Deltabin makes and applies binary patches in the style of bsdiff, which
+// work well for executables and other files where a small change shifts and
+// slightly alters a lot of the bytes around it. That makes it a good fit for
+// shipping software updates.
+//
+// Patches carry checksums of both files. Applying one checks the old file
+// before writing anything, and the new file as it's written, so a patch
+// applied to the wrong file, or a corrupted one, never goes unnoticed.
+//
+// Examples:
+//
+//	patch := deltabin.Diff(old, new)
+//	restored, err := deltabin.Patch(old, patch)
+//		// restored is new
+package deltabin
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+var (
+	// ErrCorrupt is returned when a patch can't be read.
+	ErrCorrupt = errors.New("deltabin: corrupt patch")
+
+	// ErrMismatch is returned when the patch is applied to another old
+	// file than the one it was made for.
+	ErrMismatch = errors.New("deltabin: patch does not match the old file")
+
+	// ErrChecksum is returned when the patched file doesn't come out as
+	// the new file the patch was made from.
+	ErrChecksum = errors.New("deltabin: checksum mismatch")
+)
+
+// magic starts every patch.
+var magic = []byte("DELTABIN\x01")
+
+// header is what a patch starts with, after the magic bytes.
+type header struct {
+	OldSize, NewSize uint64
+	OldSum, NewSum   [sha256.Size]byte
+}
+
+// Diff returns the patch turning old into new.
+func Diff(old, new []byte) []byte {
+	var b bytes.Buffer
+	DiffTo(&b, old, new)
+	return b.Bytes()
+}
+
+// DiffTo writes the patch turning old into new to w.
+//
+// The patch is a list of instructions, each adding bytes of the old file to
+// bytes of the patch, then appending bytes found only in the new file, then
+// seeking in the old file. The short runs of differences left by the adding
+// compress very well, so the whole list is compressed.
+func DiffTo(w io.Writer, old, new []byte) error {
+	h := header{
+		OldSize: uint64(len(old)),
+		NewSize: uint64(len(new)),
+		OldSum:  sha256.Sum256(old),
+		NewSum:  sha256.Sum256(new),
+	}
+	if _, err := w.Write(magic); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, h); err != nil {
+		return err
+	}
+
+	z, _ := flate.NewWriter(w, flate.BestCompression)
+	for _, c := range controls(old, new) {
+		var b []byte
+		b = binary.AppendUvarint(b, uint64(c.add))
+		b = binary.AppendUvarint(b, uint64(c.extra))
+		b = binary.AppendVarint(b, int64(c.seek))
+		for i := 0; i < c.add; i++ {
+			b = append(b, new[c.newPos+i]-old[c.oldPos+i])
+		}
+		b = append(b, new[c.newPos+c.add:c.newPos+c.add+c.extra]...)
+		if _, err := z.Write(b); err != nil {
+			return err
+		}
+	}
+	return z.Close()
+}
+
+// control is a single instruction of the patch. It adds add bytes of the old
+// file at oldPos to the patch, appends extra bytes of the new file, and then
+// moves on in the old file by seek. newPos is where in the new file it
+// starts, which Diff uses to fill the patch in.
+type control struct {
+	add, extra, seek int
+	oldPos, newPos   int
+}
+
+// controls finds the instructions turning old into new. This is the
+// algorithm of bsdiff: look for exact matches using the suffix array of
+// the old file, then extend them forwards and backwards into stretches
+// which mostly, but not entirely, match.
+func controls(old, new []byte) []control {
+	I := sufsort(old)
+	var cs []control
+
+	scan, n, pos := 0, 0, 0
+	lastScan, lastPos, lastOffset := 0, 0, 0
+
+	for scan < len(new) {
+		score := 0
+		scan += n
+		for scsc := scan; scan < len(new); scan++ {
+			pos, n = search(I, old, new[scan:], 0, len(old))
+
+			for ; scsc < scan+n; scsc++ {
+				if scsc+lastOffset < len(old) && old[scsc+lastOffset] == new[scsc] {
+					score++
+				}
+			}
+			if (n == score && n != 0) || n > score+8 {
+				break
+			}
+			if scan+lastOffset < len(old) && old[scan+lastOffset] == new[scan] {
+				score--
+			}
+		}
+
+		if n == score && scan != len(new) {
+			continue
+		}
+
+		// Extend the previous match forwards and this one backwards,
+		// as long as more than half of the bytes keep matching.
+		s, best, forward := 0, 0, 0
+		for i := 0; lastScan+i < scan && lastPos+i < len(old); {
+			if old[lastPos+i] == new[lastScan+i] {
+				s++
+			}
+			i++
+			if s*2-i > best*2-forward {
+				best, forward = s, i
+			}
+		}
+
+		backward := 0
+		if scan < len(new) {
+			s, best := 0, 0
+			for i := 1; scan >= lastScan+i && pos >= i; i++ {
+				if old[pos-i] == new[scan-i] {
+					s++
+				}
+				if s*2-i > best*2-backward {
+					best, backward = s, i
+				}
+			}
+		}
+
+		// Where the two overlap, split them where it's best for both.
+		if lastScan+forward > scan-backward {
+			overlap := lastScan + forward - (scan - backward)
+			s, best, cut := 0, 0, 0
+			for i := 0; i < overlap; i++ {
+				if new[lastScan+forward-overlap+i] == old[lastPos+forward-overlap+i] {
+					s++
+				}
+				if new[scan-backward+i] == old[pos-backward+i] {
+					s--
+				}
+				if s > best {
+					best, cut = s, i+1
+				}
+			}
+			forward += cut - overlap
+			backward -= cut
+		}
+
+		cs = append(cs, control{
+			add:    forward,
+			extra:  scan - backward - (lastScan + forward),
+			seek:   pos - backward - (lastPos + forward),
+			oldPos: lastPos,
+			newPos: lastScan,
+		})
+
+		lastScan, lastPos, lastOffset = scan-backward, pos-backward, pos-scan
+	}
+
+	return cs
+}
+
+// Patch applies the patch to old and returns the new file.
+func Patch(old, patch []byte) ([]byte, error) {
+	var b bytes.Buffer
+	err := Apply(&b, bytes.NewReader(old), bytes.NewReader(patch))
+	return b.Bytes(), err
+}
+
+// Apply applies the patch to the old file, streaming the new one to w. Only
+// the patch is read as a stream, the old file is read from wherever the
+// patch points. The old file is checked in full before anything is written,
+// while the new file can only be checked once it's been written, so w may
+// get some output even if ErrChecksum is returned.
+func Apply(w io.Writer, old io.ReaderAt, patch io.Reader) error {
+	r := bufio.NewReader(patch)
+
+	m := make([]byte, len(magic))
+	if _, err := io.ReadFull(r, m); err != nil || !bytes.Equal(m, magic) {
+		return ErrCorrupt
+	}
+	var h header
+	if err := binary.Read(r, binary.BigEndian, &h); err != nil {
+		return ErrCorrupt
+	}
+
+	sum := sha256.New()
+	if _, err := io.Copy(sum, io.NewSectionReader(old, 0, int64(h.OldSize))); err != nil {
+		return err
+	}
+	if !bytes.Equal(sum.Sum(nil), h.OldSum[:]) {
+		return ErrMismatch
+	}
+
+	z := bufio.NewReader(flate.NewReader(r))
+	bw := bufio.NewWriter(w)
+	sum.Reset()
+	out := io.MultiWriter(bw, sum)
+
+	var oldPos, newPos int64
+	buf := make([]byte, 32*1024)
+	for uint64(newPos) < h.NewSize {
+		add, err1 := binary.ReadUvarint(z)
+		extra, err2 := binary.ReadUvarint(z)
+		seek, err3 := binary.ReadVarint(z)
+		if err1 != nil || err2 != nil || err3 != nil || add+extra > h.NewSize-uint64(newPos) ||
+			oldPos < 0 || uint64(oldPos)+add > h.OldSize {
+			return ErrCorrupt
+		}
+
+		for left := int(add); left > 0; {
+			chunk := buf[:min(left, len(buf))]
+			if n, err := old.ReadAt(chunk, oldPos); n < len(chunk) {
+				if err == nil || err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				return err
+			}
+			for i := range chunk {
+				c, err := z.ReadByte()
+				if err != nil {
+					return ErrCorrupt
+				}
+				chunk[i] += c
+			}
+			if _, err := out.Write(chunk); err != nil {
+				return err
+			}
+			left -= len(chunk)
+			oldPos += int64(len(chunk))
+		}
+
+		if _, err := io.CopyN(out, z, int64(extra)); err != nil {
+			if err == io.EOF {
+				return ErrCorrupt
+			}
+			return err
+		}
+
+		newPos += int64(add + extra)
+		oldPos += seek
+	}
+
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	if !bytes.Equal(sum.Sum(nil), h.NewSum[:]) {
+		return ErrChecksum
+	}
+	return nil
+}
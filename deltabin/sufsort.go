@@ -0,0 +1,175 @@
+package deltabin
+
+import "bytes"
+
+// sufsort builds the suffix array of data using the qsufsort algorithm of
+// Larsson and Sadakane, the same bsdiff uses. The array has one more entry
+// than data, the first one being the empty suffix.
+func sufsort(data []byte) []int {
+	n := len(data)
+	I := make([]int, n+1)
+	V := make([]int, n+1)
+
+	var buckets [256]int
+	for _, c := range data {
+		buckets[c]++
+	}
+	for i := 1; i < 256; i++ {
+		buckets[i] += buckets[i-1]
+	}
+	for i := 255; i > 0; i-- {
+		buckets[i] = buckets[i-1]
+	}
+	buckets[0] = 0
+
+	for i, c := range data {
+		buckets[c]++
+		I[buckets[c]] = i
+	}
+	I[0] = n
+	for i, c := range data {
+		V[i] = buckets[c]
+	}
+	V[n] = 0
+	for i := 1; i < 256; i++ {
+		if buckets[i] == buckets[i-1]+1 {
+			I[buckets[i]] = -1
+		}
+	}
+	I[0] = -1
+
+	// Groups of suffixes sharing the first h bytes get sorted by the
+	// next h, doubling h until every suffix is in a group of its own.
+	// Sorted runs are marked with their negated length.
+	for h := 1; I[0] != -(n + 1); h += h {
+		l := 0
+		i := 0
+		for i < n+1 {
+			if I[i] < 0 {
+				l -= I[i]
+				i -= I[i]
+				continue
+			}
+			if l > 0 {
+				I[i-l] = -l
+			}
+			l = V[I[i]] + 1 - i
+			split(I, V, i, l, h)
+			i += l
+			l = 0
+		}
+		if l > 0 {
+			I[i-l] = -l
+		}
+	}
+
+	for i := 0; i < n+1; i++ {
+		I[V[i]] = i
+	}
+	return I
+}
+
+// split sorts a group of suffixes by their rank h bytes further on, using a
+// ternary quicksort, and gives the resulting groups their new ranks.
+func split(I, V []int, start, length, h int) {
+	if length < 16 {
+		for k := start; k < start+length; {
+			j := 1
+			x := V[I[k]+h]
+			for i := 1; k+i < start+length; i++ {
+				if v := V[I[k+i]+h]; v < x {
+					x = v
+					j = 0
+				}
+				if V[I[k+i]+h] == x {
+					I[k+j], I[k+i] = I[k+i], I[k+j]
+					j++
+				}
+			}
+			for i := 0; i < j; i++ {
+				V[I[k+i]] = k + j - 1
+			}
+			if j == 1 {
+				I[k] = -1
+			}
+			k += j
+		}
+		return
+	}
+
+	x := V[I[start+length/2]+h]
+	jj, kk := 0, 0
+	for i := start; i < start+length; i++ {
+		if V[I[i]+h] < x {
+			jj++
+		}
+		if V[I[i]+h] == x {
+			kk++
+		}
+	}
+	jj += start
+	kk += jj
+
+	i, j, k := start, 0, 0
+	for i < jj {
+		switch v := V[I[i]+h]; {
+		case v < x:
+			i++
+		case v == x:
+			I[i], I[jj+j] = I[jj+j], I[i]
+			j++
+		default:
+			I[i], I[kk+k] = I[kk+k], I[i]
+			k++
+		}
+	}
+	for jj+j < kk {
+		if V[I[jj+j]+h] == x {
+			j++
+		} else {
+			I[jj+j], I[kk+k] = I[kk+k], I[jj+j]
+			k++
+		}
+	}
+
+	if jj > start {
+		split(I, V, start, jj-start, h)
+	}
+	for i := 0; i < kk-jj; i++ {
+		V[I[jj+i]] = kk - 1
+	}
+	if jj == kk-1 {
+		I[jj] = -1
+	}
+	if start+length > kk {
+		split(I, V, kk, start+length-kk, h)
+	}
+}
+
+// search finds the suffix of old sharing the longest prefix with target,
+// using binary search over the suffix array between st and en.
+func search(I []int, old, target []byte, st, en int) (pos, n int) {
+	for en-st >= 2 {
+		x := st + (en-st)/2
+		if bytes.Compare(old[I[x]:I[x]+min(len(old)-I[x], len(target))], target[:min(len(old)-I[x], len(target))]) < 0 {
+			st = x
+		} else {
+			en = x
+		}
+	}
+
+	x, y := matchlen(old[I[st]:], target), matchlen(old[I[en]:], target)
+	if x > y {
+		return I[st], x
+	}
+	return I[en], y
+}
+
+// matchlen returns the length of the common prefix of a and b.
+func matchlen(a, b []byte) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,197 @@
+// Deltadocx compares Word documents in the .docx format paragraph by
+// paragraph. The text of every paragraph is taken out of both documents,
+// the paragraphs are lined up, and those which changed are compared word by
+// word by delta, for comparing contracts and other documents where only
+// the words matter.
+//
+// Examples:
+//
+//	prev, _ := os.ReadFile("contract-v1.docx")
+//	curr, _ := os.ReadFile("contract-v2.docx")
+//	deltadocx.Compare(prev, curr, true)
+//		// []Paragraph{{Prev: 1, Curr: 1, Diff: "Terms"}, {Prev: 2, Curr: 2, Changed: true, Diff: "Payment is due within ---30--- +++14+++ days."}}, nil
+package deltadocx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/nkrs/delta"
+)
+
+// ErrNotDocx is returned for files which aren't Word documents.
+var ErrNotDocx = errors.New("deltadocx: not a .docx file")
+
+// namespace is the namespace of the elements of Word documents.
+const namespace = "http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+
+// Paragraph is a paragraph of either document. Prev and Curr are its
+// numbers in the previous and the current document, starting at 1, with 0
+// for paragraphs only the other has. Changed tells whether the text of the
+// paragraph changed, and Diff holds the delta of its texts, or the text
+// itself when it didn't.
+type Paragraph struct {
+	Prev, Curr int
+	Changed    bool
+	Diff       string
+}
+
+// Compare extracts the paragraphs of the two documents and compares them,
+// returning all paragraphs of both in order, with the deltas of their text
+// as HTML or plain text like delta.Calculate. Paragraphs which changed are
+// paired up with those which took their place, while paragraphs which were
+// added or removed as a whole are shown as such. Options are passed on to
+// delta.
+func Compare(prev, curr []byte, plaintext bool, opts ...delta.Option) ([]Paragraph, error) {
+	pp, err := Text(prev)
+	if err != nil {
+		return nil, err
+	}
+	cp, err := Text(curr)
+	if err != nil {
+		return nil, err
+	}
+
+	var paragraphs []Paragraph
+	i, j := 0, 0
+	pair := func(p, c bool) {
+		para := Paragraph{Changed: !p || !c}
+		var old, new []string
+		if p {
+			i++
+			para.Prev, old = i, []string{pp[i-1]}
+		}
+		if c {
+			j++
+			para.Curr, new = j, []string{cp[j-1]}
+		}
+		if p && c {
+			para.Changed = pp[i-1] != cp[j-1]
+			para.Diff = delta.Calculate(pp[i-1], cp[j-1], plaintext, opts...)
+		} else {
+			para.Diff = delta.CalculateTokens(old, new, plaintext, opts...)
+		}
+		paragraphs = append(paragraphs, para)
+	}
+
+	edits := delta.DiffSlices(pp, cp)
+	for k := 0; k < len(edits); k++ {
+		e := edits[k]
+		switch e.Op {
+		case delta.Equal:
+			for range e.Items {
+				pair(true, true)
+			}
+		case delta.Delete:
+			inserted := 0
+			if k+1 < len(edits) && edits[k+1].Op == delta.Insert {
+				inserted = len(edits[k+1].Items)
+				k++
+			}
+			for n := 0; n < max(len(e.Items), inserted); n++ {
+				pair(n < len(e.Items), n < inserted)
+			}
+		case delta.Insert:
+			for range e.Items {
+				pair(false, true)
+			}
+		}
+	}
+	return paragraphs, nil
+}
+
+// Text extracts the text of the paragraphs of the body of a document, tabs
+// and line breaks included, leaving out empty paragraphs. Text in tracked
+// deletions is left out as well, while text in tracked insertions is kept,
+// as if all changes were accepted.
+func Text(docx []byte) ([]string, error) {
+	r, err := zip.NewReader(bytes.NewReader(docx), int64(len(docx)))
+	if err != nil {
+		return nil, ErrNotDocx
+	}
+	var document *zip.File
+	for _, f := range r.File {
+		if f.Name == "word/document.xml" {
+			document = f
+		}
+	}
+	if document == nil {
+		return nil, ErrNotDocx
+	}
+	rc, err := document.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return paragraphs(rc)
+}
+
+// paragraphs reads the paragraphs of word/document.xml.
+func paragraphs(r io.Reader) ([]string, error) {
+	var paragraphs []string
+	var text strings.Builder
+	inText := false
+	// Tabs and breaks only count within runs, not where properties
+	// define tab stops and such.
+	runs, props := 0, 0
+
+	d := xml.NewDecoder(r)
+	for {
+		t, err := d.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("deltadocx: reading document: %w", err)
+		}
+
+		switch t := t.(type) {
+		case xml.StartElement:
+			if t.Name.Space != namespace {
+				continue
+			}
+			switch t.Name.Local {
+			case "r":
+				runs++
+			case "pPr", "rPr":
+				props++
+			case "t":
+				inText = true
+			case "tab":
+				if runs > 0 && props == 0 {
+					text.WriteString("\t")
+				}
+			case "br", "cr":
+				if runs > 0 && props == 0 {
+					text.WriteString("\n")
+				}
+			}
+		case xml.EndElement:
+			if t.Name.Space != namespace {
+				continue
+			}
+			switch t.Name.Local {
+			case "r":
+				runs--
+			case "pPr", "rPr":
+				props--
+			case "t":
+				inText = false
+			case "p":
+				if strings.TrimSpace(text.String()) != "" {
+					paragraphs = append(paragraphs, text.String())
+				}
+				text.Reset()
+			}
+		case xml.CharData:
+			if inText {
+				text.Write(t)
+			}
+		}
+	}
+	return paragraphs, nil
+}
@@ -0,0 +1,18 @@
+package deltadocx
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParagraphs(t *testing.T) {
+	doc := `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:pPr><w:tabs><w:tab w:val="left" w:pos="720"/></w:tabs></w:pPr><w:r><w:t>a</w:t><w:tab/><w:t>b</w:t></w:r></w:p>
+<w:p><w:r><w:rPr><w:b/></w:rPr><w:t>c</w:t><w:br/><w:t>d</w:t></w:r></w:p>
+</w:body></w:document>`
+	got, err := paragraphs(strings.NewReader(doc))
+	if want := []string{"a\tb", "c\nd"}; err != nil || !slices.Equal(got, want) {
+		t.Errorf("paragraphs = %q, %v, want %q", got, err, want)
+	}
+}
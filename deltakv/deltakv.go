@@ -0,0 +1,130 @@
+// Deltakv compares files of keys and values, such as .env files and Java
+// properties, key by key. Keys which were added, removed or given another
+// value are reported, with the values compared word by word by delta, while
+// moving keys around and editing comments don't count as changes.
+//
+// Examples:
+//
+//	deltakv.Diff("# db\nDB_HOST=localhost\nDEBUG=true\n", "DB_HOST=db.internal\nLOG_LEVEL=info\n")
+//		// []Change{
+//		// 	{Key: "DB_HOST", Kind: delta.Replacement, Old: "localhost", New: "db.internal", Diff: "---localhost--- +++db.internal+++"},
+//		// 	{Key: "LOG_LEVEL", Kind: delta.Insertion, New: "info", Diff: "+++info+++"},
+//		// 	{Key: "DEBUG", Kind: delta.Deletion, Old: "true", Diff: "---true---"},
+//		// }
+package deltakv
+
+import (
+	"strings"
+
+	"github.com/nkrs/delta"
+)
+
+// Change is a key which changed. Kind is delta.Insertion for keys which
+// were added, delta.Deletion for those which were removed, and
+// delta.Replacement for those given another value. Diff holds the plain
+// text delta of the values.
+type Change struct {
+	Key      string
+	Kind     delta.Kind
+	Old, New string
+	Diff     string
+}
+
+// String returns the change in a form suitable for logging.
+func (c Change) String() string {
+	return c.Key + ": " + c.Diff
+}
+
+// Diff compares the keys of the two files, returning the changes in the
+// order the keys come in the current file, followed by the keys only the
+// previous one has. Options are passed on to delta when comparing the
+// values.
+func Diff(prev, curr string, opts ...delta.Option) []Change {
+	pk, pv := Parse(prev)
+	ck, cv := Parse(curr)
+
+	var changes []Change
+	for _, k := range ck {
+		old, ok := pv[k]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Key: k, Kind: delta.Insertion, New: cv[k], Diff: delta.CalculateValues("", cv[k], true, opts...)})
+		case old != cv[k]:
+			changes = append(changes, Change{Key: k, Kind: delta.Replacement, Old: old, New: cv[k], Diff: delta.CalculateValues(old, cv[k], true, opts...)})
+		}
+	}
+	for _, k := range pk {
+		if _, ok := cv[k]; !ok {
+			changes = append(changes, Change{Key: k, Kind: delta.Deletion, Old: pv[k], Diff: delta.CalculateValues(pv[k], "", true, opts...)})
+		}
+	}
+	return changes
+}
+
+// Parse reads the keys of a file, in the order they first come in, and
+// their values. Lines are "KEY=VALUE", optionally preceded by "export", or
+// "key: value" or "key value" as in properties files; lines starting with
+// "#" or "!" are comments. Values in quotes are unquoted, and lines ending
+// in a backslash outside of quotes go on on the next line. A key given
+// more than once takes the last value.
+func Parse(text string) (keys []string, values map[string]string) {
+	values = make(map[string]string)
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		for strings.HasSuffix(line, `\`) && !strings.HasSuffix(line, `\\`) && !unclosed(line) && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + strings.TrimSpace(lines[i])
+		}
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+
+		line = strings.TrimPrefix(line, "export ")
+		sep := strings.IndexAny(line, "=: \t")
+		if sep <= 0 {
+			if _, ok := values[line]; !ok {
+				keys = append(keys, line)
+			}
+			values[line] = ""
+			continue
+		}
+		key := strings.TrimSpace(line[:sep])
+		value := strings.TrimSpace(line[sep+1:])
+		if line[sep] == ' ' || line[sep] == '\t' {
+			// "key = value" has its separator after the spaces.
+			value = strings.TrimSpace(strings.TrimLeft(value, "=:"))
+		}
+		if _, ok := values[key]; !ok {
+			keys = append(keys, key)
+		}
+		values[key] = unquote(value)
+	}
+	return keys, values
+}
+
+// unclosed reports whether the value on the line opens a quote it doesn't
+// close, in which case a backslash ending the line is part of the value.
+func unclosed(line string) bool {
+	line = strings.TrimPrefix(line, "export ")
+	sep := strings.IndexAny(line, "=: \t")
+	if sep <= 0 {
+		return false
+	}
+	value := strings.TrimLeft(line[sep+1:], "=: \t")
+	return value != "" && (value[0] == '"' || value[0] == '\'') && strings.IndexByte(value[1:], value[0]) < 0
+}
+
+// unquote takes the value out of the quotes around it, if any, or drops
+// the comment following it otherwise.
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') {
+		if end := strings.IndexByte(value[1:], value[0]); end >= 0 {
+			return value[1 : end+1]
+		}
+	}
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return value
+}
@@ -0,0 +1,21 @@
+package deltakv
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text string
+		key  string
+		want string
+	}{
+		{"A=one \\\n  two\nB=2", "A", "one two"},
+		{"A=\"x\\\nB=2", "B", "2"},
+		{"export A='x \\\nB=2", "B", "2"},
+		{"A=\"x y\" # note", "A", "x y"},
+	}
+	for _, tt := range tests {
+		if _, values := Parse(tt.text); values[tt.key] != tt.want {
+			t.Errorf("Parse(%q)[%q] = %q, want %q", tt.text, tt.key, values[tt.key], tt.want)
+		}
+	}
+}
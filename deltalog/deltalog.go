@@ -0,0 +1,99 @@
+// Deltalog compares the logs of two runs of a service. The fields which
+// differ on every run anyway, such as timestamps, process IDs and request
+// IDs, are masked on every line, so the lines are aligned on what they say
+// and the diff only shows the differences in what the service did.
+//
+// Examples:
+//
+//	deltalog.Unified(
+//		"2024-05-01T10:00:00Z pid=41 GET /users 200\n2024-05-01T10:00:01Z pid=41 GET /orders 200",
+//		"2024-05-02T09:13:37Z pid=97 GET /users 200\n2024-05-02T09:13:38Z pid=97 GET /orders 500",
+//		nil,
+//	)
+//		// " <timestamp> pid=<pid> GET /users 200\n-<timestamp> pid=<pid> GET /orders 200\n+<timestamp> pid=<pid> GET /orders 500"
+package deltalog
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/nkrs/delta"
+)
+
+// Field is a kind of volatile field of log lines. Whatever Pattern matches
+// is masked as the Name in angle brackets, or only what its first group
+// matches, when it has groups, so "pid=41" can keep its "pid=".
+type Field struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// Fields are the volatile fields most logs have, and what Unified masks
+// when it isn't given any: timestamps in ISO 8601 and syslog formats, UUIDs,
+// the IDs of requests and traces, process IDs, and long hexadecimal
+// numbers.
+var Fields = []Field{
+	{"timestamp", regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?`)},
+	{"timestamp", regexp.MustCompile(`\b(?:Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec) [ \d]\d \d{2}:\d{2}:\d{2}\b`)},
+	{"timestamp", regexp.MustCompile(`\b\d{2}:\d{2}:\d{2}(?:[.,]\d+)?\b`)},
+	{"uuid", regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`)},
+	{"id", regexp.MustCompile(`(?i)\b(?:request|req|trace|span|correlation)[_-]?id["']?\s*[=:]\s*["']?([\w.-]+)`)},
+	{"pid", regexp.MustCompile(`(?i)\bpid\s*[=:]\s*(\d+)`)},
+	{"pid", regexp.MustCompile(`\b[\w.-]+\[(\d+)\]:`)},
+	{"hex", regexp.MustCompile(`\b(?:0x)?[0-9a-fA-F]{16,}\b`)},
+}
+
+// Mask masks the fields on every line of the log, in the order they're
+// given.
+func Mask(log string, fields []Field) string {
+	lines := strings.Split(log, "\n")
+	for i, line := range lines {
+		for _, f := range fields {
+			line = mask(line, f)
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// mask masks a field on a line.
+func mask(line string, f Field) string {
+	matches := f.Pattern.FindAllStringSubmatchIndex(line, -1)
+	if matches == nil {
+		return line
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if len(m) > 2 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		b.WriteString(line[last:start])
+		b.WriteString("<" + f.Name + ">")
+		last = end
+	}
+	b.WriteString(line[last:])
+	return b.String()
+}
+
+// Unified masks the fields of both logs, Fields when fields is nil, and
+// compares them line by line like delta.Unified, which options are passed
+// on to.
+func Unified(prev, curr string, fields []Field, opts ...delta.Option) string {
+	if fields == nil {
+		fields = Fields
+	}
+	return delta.Unified(Mask(prev, fields), Mask(curr, fields), opts...)
+}
+
+// SideBySide masks the fields of both logs, Fields when fields is nil, and
+// compares them line by line like delta.SideBySide, which options are
+// passed on to.
+func SideBySide(prev, curr string, fields []Field, opts ...delta.Option) string {
+	if fields == nil {
+		fields = Fields
+	}
+	return delta.SideBySide(Mask(prev, fields), Mask(curr, fields), opts...)
+}
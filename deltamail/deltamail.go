@@ -0,0 +1,187 @@
+// Deltamail compares two email messages the way people read them: the
+// headers as pairs of names and values, and the body as prose, word by word,
+// once it's decoded from quoted-printable or base64. Support tools use it to
+// compare the revisions of the templates of the emails they send.
+//
+// Examples:
+//
+//	deltamail.Compare(prev, curr, true)
+//		// &Diff{
+//		// 	Headers: []Change{{Header: "Subject", Old: "Your order", New: "Your order has shipped", Diff: "Your order +++has shipped+++"}},
+//		// 	Body: "Hello ---Ann,--- +++Bob,+++ ...",
+//		// }, nil
+package deltamail
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nkrs/delta"
+)
+
+// Change is a header which changed. Header is its name, followed by "[i]"
+// when the message has it more than once, and Old or New is empty when it's
+// missing on that side. Values are compared decoded, so a Subject encoded
+// as "=?UTF-8?Q?...?=" reads as the text it stands for. Diff holds the plain
+// text delta of the two.
+type Change struct {
+	Header   string
+	Old, New string
+	Diff     string
+}
+
+// String returns the change in a form suitable for logging.
+func (c Change) String() string {
+	return c.Header + ": " + c.Diff
+}
+
+// Diff is the difference between two messages: the headers which changed,
+// by name, and the delta of the bodies, which is empty when they're the
+// same.
+type Diff struct {
+	Headers []Change
+	Body    string
+}
+
+// Compare parses the two messages and compares them, returning the delta
+// of the bodies as HTML or plain text like delta.Calculate. The bodies are
+// decoded first; of multipart messages, the text/plain parts are compared,
+// or the other text parts when there are none. Options are passed on to
+// delta.
+func Compare(prev, curr string, plaintext bool, opts ...delta.Option) (*Diff, error) {
+	p, err := mail.ReadMessage(strings.NewReader(prev))
+	if err != nil {
+		return nil, err
+	}
+	c, err := mail.ReadMessage(strings.NewReader(curr))
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Diff{Headers: headers(p.Header, c.Header, opts)}
+	pb, err := body(p.Header.Get("Content-Type"), p.Header.Get("Content-Transfer-Encoding"), p.Body)
+	if err != nil {
+		return nil, err
+	}
+	cb, err := body(c.Header.Get("Content-Type"), c.Header.Get("Content-Transfer-Encoding"), c.Body)
+	if err != nil {
+		return nil, err
+	}
+	if pb != cb {
+		d.Body = delta.Calculate(pb, cb, plaintext, opts...)
+	}
+	return d, nil
+}
+
+// headers compares the headers by name, in the order of the names, the
+// ith value of a header in either message with the ith one in the other.
+func headers(prev, curr mail.Header, opts []delta.Option) []Change {
+	names := make([]string, 0, len(prev)+len(curr))
+	for name := range prev {
+		names = append(names, name)
+	}
+	for name := range curr {
+		if _, ok := prev[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []Change
+	for _, name := range names {
+		pv, cv := prev[name], curr[name]
+		for i := 0; i < len(pv) || i < len(cv); i++ {
+			var old, new string
+			if i < len(pv) {
+				old = decode(pv[i])
+			}
+			if i < len(cv) {
+				new = decode(cv[i])
+			}
+			if old == new {
+				continue
+			}
+			c := Change{Header: name, Old: old, New: new}
+			if len(pv) > 1 || len(cv) > 1 {
+				c.Header += "[" + strconv.Itoa(i) + "]"
+			}
+			c.Diff = delta.CalculateValues(old, new, true, opts...)
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// decode decodes the encoded words of a header, or leaves it as it is when
+// they can't be.
+func decode(value string) string {
+	var d mime.WordDecoder
+	if s, err := d.DecodeHeader(value); err == nil {
+		return s
+	}
+	return value
+}
+
+// body reads the text of a body of the given content type and transfer
+// encoding, decoded.
+func body(contentType, encoding string, r io.Reader) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "quoted-printable":
+		r = quotedprintable.NewReader(r)
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, r)
+	}
+
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return "", fmt.Errorf("deltamail: reading body: %w", err)
+		}
+		return strings.ReplaceAll(string(b), "\r\n", "\n"), nil
+	}
+
+	var plain, other []string
+	parts := multipart.NewReader(r, params["boundary"])
+	for {
+		part, err := parts.NextRawPart()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return "", fmt.Errorf("deltamail: reading body: %w", err)
+		}
+		partType := part.Header.Get("Content-Type")
+		if partType == "" {
+			partType = "text/plain"
+		}
+		t, _, _ := mime.ParseMediaType(partType)
+		if !strings.HasPrefix(t, "text/") && !strings.HasPrefix(t, "multipart/") {
+			continue
+		}
+		text, err := body(partType, part.Header.Get("Content-Transfer-Encoding"), part)
+		if err != nil {
+			return "", err
+		}
+		if t == "text/plain" || strings.HasPrefix(t, "multipart/") {
+			plain = append(plain, text)
+		} else {
+			other = append(other, text)
+		}
+	}
+	if len(plain) == 0 {
+		plain = other
+	}
+	return strings.Join(plain, "\n\n"), nil
+}
@@ -0,0 +1,128 @@
+// Deltarich compares the documents of rich text editors, Quill deltas and
+// ProseMirror documents in their JSON form, and returns a document of the
+// same kind showing the changes, for the editor to display as it does its
+// own formatting. The text is compared word by word like delta does, while
+// changes of formatting alone, such as a word turned bold, are told apart
+// from changes of the text.
+//
+// Insertions and deletions are marked the way each editor marks text: in
+// Quill with the attributes "insertion" and "deletion", and "formatting"
+// for text formatted differently, and in ProseMirror with marks of those
+// types, which the editor's schema has to define.
+//
+// Examples:
+//
+//	deltarich.Quill([]byte(`{"ops":[{"insert":"hello world\n"}]}`), []byte(`{"ops":[{"insert":"hello "},{"insert":"earth","attributes":{"bold":true}},{"insert":"\n"}]}`))
+//		// `{"ops":[{"insert":"hello "},{"insert":"world","attributes":{"deletion":true}},{"insert":"earth","attributes":{"bold":true,"insertion":true}},{"insert":"\n"}]}`, nil
+package deltarich
+
+import (
+	"encoding/json"
+	"regexp"
+
+	"github.com/nkrs/delta"
+)
+
+// Marks of the changes.
+const (
+	Insertion  = "insertion"
+	Deletion   = "deletion"
+	Formatting = "formatting"
+)
+
+// token is a word, the whitespace between words, or something else taking
+// the place of a character, such as an image or the end of a paragraph,
+// along with its formatting. Tokens are compared by their text, or the
+// JSON of what they are otherwise, while the format is only compared once
+// they match.
+type token struct {
+	text   string
+	object string
+	format string
+	source any
+}
+
+// key returns what the token is compared by.
+func (t token) key() string {
+	if t.object != "" {
+		return "\x00" + t.object
+	}
+	return t.text
+}
+
+// change is a token of either document, with what happened to it. Tokens
+// found in both come from the current document, and are formatted when
+// their format changed.
+type change struct {
+	token
+	op        delta.Operation
+	formatted bool
+}
+
+// compare diffs the tokens of the documents.
+func compare(prev, curr []token) []change {
+	pk, ck := make([]string, len(prev)), make([]string, len(curr))
+	for i, t := range prev {
+		pk[i] = t.key()
+	}
+	for j, t := range curr {
+		ck[j] = t.key()
+	}
+
+	var changes []change
+	i, j := 0, 0
+	for _, e := range delta.DiffSlices(pk, ck) {
+		for range e.Items {
+			switch e.Op {
+			case delta.Equal:
+				changes = append(changes, change{token: curr[j], op: delta.Equal, formatted: prev[i].format != curr[j].format})
+				i, j = i+1, j+1
+			case delta.Delete:
+				changes = append(changes, change{token: prev[i], op: delta.Delete})
+				i++
+			case delta.Insert:
+				changes = append(changes, change{token: curr[j], op: delta.Insert})
+				j++
+			}
+		}
+	}
+	return changes
+}
+
+// mark returns the mark of the change, if any.
+func (c change) mark() string {
+	switch {
+	case c.op == delta.Insert:
+		return Insertion
+	case c.op == delta.Delete:
+		return Deletion
+	case c.formatted:
+		return Formatting
+	}
+	return ""
+}
+
+// regexpToken splits text into words, runs of whitespace and line breaks.
+var regexpToken = regexp.MustCompile(`\n|[^\S\n]+|\S+`)
+
+// words splits the text into tokens of the given format.
+func words(text, format string, source any) []token {
+	var tokens []token
+	for _, w := range regexpToken.FindAllString(text, -1) {
+		tokens = append(tokens, token{text: w, format: format, source: source})
+	}
+	return tokens
+}
+
+// canonical returns the JSON of the value with the keys of objects sorted,
+// so equal formats compare equal.
+func canonical(v any) string {
+	if v == nil {
+		return ""
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
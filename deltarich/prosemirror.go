@@ -0,0 +1,176 @@
+package deltarich
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// pmNode is a node of a ProseMirror document.
+type pmNode struct {
+	Type    string         `json:"type"`
+	Attrs   map[string]any `json:"attrs,omitempty"`
+	Content []*pmNode      `json:"content,omitempty"`
+	Text    string         `json:"text,omitempty"`
+	Marks   []pmMark       `json:"marks,omitempty"`
+}
+
+// pmMark is a mark of a ProseMirror node.
+type pmMark struct {
+	Type  string         `json:"type"`
+	Attrs map[string]any `json:"attrs,omitempty"`
+}
+
+// pmBlock is the end of a block, such as a paragraph or a heading, along
+// with the nodes it's nested in, outside in, such as a list and its item.
+type pmBlock struct {
+	node *pmNode
+	path []*pmNode
+}
+
+// ProseMirror compares two ProseMirror documents and returns a document
+// holding both the text which was deleted and the text which was inserted,
+// with marks of the type "deletion" or "insertion" respectively added to
+// them. Text which only changed its marks gets a "formatting" mark. Blocks
+// are compared by where they end, so a paragraph turned into a heading
+// keeps its text, and nodes which aren't text, such as images, compare
+// equal when their JSON does. Blocks are nested in the nodes of the
+// revision they come from.
+func ProseMirror(prev, curr []byte) ([]byte, error) {
+	pt, err := pmTokens(prev)
+	if err != nil {
+		return nil, err
+	}
+	ct, err := pmTokens(curr)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &pmNode{Type: "doc"}
+	type open struct{ source, node *pmNode }
+	stack := []open{{nil, doc}}
+	var inline []*pmNode
+
+	for _, c := range compare(pt, ct) {
+		var marks []pmMark
+		if mark := c.mark(); mark != "" {
+			marks = []pmMark{{Type: mark}}
+		}
+
+		switch source := c.source.(type) {
+		case *pmNode:
+			n := *source
+			n.Marks = append(append([]pmMark(nil), source.Marks...), marks...)
+			if n.Type == "text" {
+				n.Text = c.text
+				if last := len(inline) - 1; last >= 0 && inline[last].Type == "text" && canonical(inline[last].Marks) == canonical(n.Marks) {
+					inline[last].Text += n.Text
+					continue
+				}
+			}
+			inline = append(inline, &n)
+		case pmBlock:
+			// Reopen the nodes the block is nested in, unless
+			// they're the ones still open.
+			depth := 1
+			for depth < len(stack) && depth-1 < len(source.path) && stack[depth].source == source.path[depth-1] {
+				depth++
+			}
+			stack = stack[:depth]
+			for _, a := range source.path[depth-1:] {
+				n := &pmNode{Type: a.Type, Attrs: a.Attrs}
+				parent := stack[len(stack)-1].node
+				parent.Content = append(parent.Content, n)
+				stack = append(stack, open{a, n})
+			}
+			block := &pmNode{Type: source.node.Type, Attrs: source.node.Attrs, Content: inline}
+			parent := stack[len(stack)-1].node
+			parent.Content = append(parent.Content, block)
+			inline = nil
+		}
+	}
+	if inline != nil {
+		doc.Content = append(doc.Content, &pmNode{Type: "paragraph", Content: inline})
+	}
+	return json.Marshal(doc)
+}
+
+// pmTokens splits a ProseMirror document into tokens.
+func pmTokens(data []byte) ([]token, error) {
+	var doc pmNode
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("deltarich: reading ProseMirror document: %w", err)
+	}
+	pmPrune(&doc)
+	var tokens []token
+	pmWalk(&doc, nil, &tokens)
+	return tokens, nil
+}
+
+// pmPrune drops the null children of the node and those below it, which
+// ProseMirror never writes but JSON allows.
+func pmPrune(node *pmNode) {
+	node.Content = slices.DeleteFunc(node.Content, func(child *pmNode) bool {
+		return child == nil
+	})
+	for _, child := range node.Content {
+		pmPrune(child)
+	}
+}
+
+// pmWalk adds the tokens of the blocks within the node, whose ancestors
+// below the document are those of the path.
+func pmWalk(node *pmNode, path []*pmNode, tokens *[]token) {
+	if !pmTextblock(node) {
+		if node.Type != "doc" || path != nil {
+			path = append(path[:len(path):len(path)], node)
+		}
+		for _, child := range node.Content {
+			if len(child.Content) == 0 && child.Type != "text" {
+				// Blocks without content, such as rules.
+				*tokens = append(*tokens, pmEnd(child, path))
+				continue
+			}
+			pmWalk(child, path, tokens)
+		}
+		return
+	}
+
+	for _, child := range node.Content {
+		if child.Type == "text" {
+			*tokens = append(*tokens, words(child.Text, canonical(child.Marks), child)...)
+			continue
+		}
+		leaf := *child
+		leaf.Marks = nil
+		*tokens = append(*tokens, token{object: canonical(leaf), format: canonical(child.Marks), source: child})
+	}
+	*tokens = append(*tokens, pmEnd(node, path))
+}
+
+// pmTextblock tells whether the node holds inline content: text, or only
+// nodes without content of their own, such as images.
+func pmTextblock(node *pmNode) bool {
+	if node.Type == "doc" || len(node.Content) == 0 {
+		return false
+	}
+	for _, child := range node.Content {
+		if child.Type == "text" {
+			return true
+		}
+		if len(child.Content) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// pmEnd returns the token ending the block. Ends of blocks all compare
+// equal, with the type and attributes of the block as their format.
+func pmEnd(node *pmNode, path []*pmNode) token {
+	return token{
+		object: "block",
+		format: canonical(pmNode{Type: node.Type, Attrs: node.Attrs}),
+		source: pmBlock{node, path},
+	}
+}
@@ -0,0 +1,13 @@
+package deltarich
+
+import "testing"
+
+func TestProseMirrorNull(t *testing.T) {
+	prev := `{"type":"doc","content":[null]}`
+	curr := `{"type":"doc","content":[{"type":"paragraph","content":[null,{"type":"text","text":"hi"}]}]}`
+	got, err := ProseMirror([]byte(prev), []byte(curr))
+	want := `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"hi","marks":[{"type":"insertion"}]}]}]}`
+	if err != nil || string(got) != want {
+		t.Errorf("ProseMirror = %s, %v, want %s", got, err, want)
+	}
+}
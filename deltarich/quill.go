@@ -0,0 +1,89 @@
+package deltarich
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// quillOp is an operation of a Quill delta. Documents are made of inserts
+// only: text, or an object embedded in it, such as an image.
+type quillOp struct {
+	Insert     any            `json:"insert,omitempty"`
+	Delete     any            `json:"delete,omitempty"`
+	Retain     any            `json:"retain,omitempty"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// quillDelta is a Quill delta.
+type quillDelta struct {
+	Ops []quillOp `json:"ops"`
+}
+
+// Quill compares two Quill documents, deltas made of inserts only, and
+// returns a document holding both the text which was deleted and the text
+// which was inserted, with the attribute "deletion" or "insertion" set to
+// true respectively. Text which only changed its attributes has the
+// attribute "formatting" set to true. Line breaks are compared like words,
+// and with them the formats of lines, such as headers, while embeds compare
+// equal when their JSON does.
+func Quill(prev, curr []byte) ([]byte, error) {
+	pt, err := quillTokens(prev)
+	if err != nil {
+		return nil, err
+	}
+	ct, err := quillTokens(curr)
+	if err != nil {
+		return nil, err
+	}
+
+	var out quillDelta
+	for _, c := range compare(pt, ct) {
+		op := c.source.(quillOp)
+		attrs := op.Attributes
+		if mark := c.mark(); mark != "" {
+			attrs = make(map[string]any, len(op.Attributes)+1)
+			for k, v := range op.Attributes {
+				attrs[k] = v
+			}
+			attrs[mark] = true
+		}
+
+		var insert any = c.text
+		if c.object != "" {
+			insert = op.Insert
+		}
+		if n := len(out.Ops) - 1; n >= 0 && c.object == "" && canonical(attrs) == canonical(out.Ops[n].Attributes) {
+			if text, ok := out.Ops[n].Insert.(string); ok {
+				out.Ops[n].Insert = text + c.text
+				continue
+			}
+		}
+		out.Ops = append(out.Ops, quillOp{Insert: insert, Attributes: attrs})
+	}
+	if out.Ops == nil {
+		out.Ops = []quillOp{}
+	}
+	return json.Marshal(out)
+}
+
+// quillTokens splits a Quill document into tokens.
+func quillTokens(data []byte) ([]token, error) {
+	var d quillDelta
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("deltarich: reading Quill delta: %w", err)
+	}
+
+	var tokens []token
+	for i, op := range d.Ops {
+		if op.Insert == nil || op.Delete != nil || op.Retain != nil {
+			return nil, fmt.Errorf("deltarich: op %d of the Quill delta isn't an insert", i)
+		}
+		format := canonical(op.Attributes)
+		if text, ok := op.Insert.(string); ok {
+			tokens = append(tokens, words(text, format, op)...)
+			continue
+		}
+		tokens = append(tokens, token{object: canonical(op.Insert), format: format, source: op})
+	}
+	return tokens, nil
+}
@@ -0,0 +1,201 @@
+// Package sql stores the history of documents as delta chains in any
+// database supported by database/sql. Every document gets its first
+// revision in full, then a patch for every revision after it, turning the
+// one before into it. The latest revision is kept in full as well, so
+// adding to a chain doesn't have to go through all of it.
+//
+// Examples:
+//
+//	s := sql.New(db, sql.SQLite, "")
+//	s.Init(ctx)
+//	s.Put(ctx, "readme", "hello world")
+//	s.Put(ctx, "readme", "hello earth")
+//	s.Revision(ctx, "readme", 0)
+//		// "hello world"
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/nkrs/delta"
+)
+
+// ErrNotFound is returned when asking for a revision the store doesn't have.
+var ErrNotFound = errors.New("deltastore: revision not found")
+
+// Dialect holds what differs between databases: the types of the columns
+// holding text and bytes, and how the n-th parameter of a query, from 1, is
+// written.
+type Dialect struct {
+	Text, Bytes string
+	Placeholder func(n int) string
+}
+
+// Dialects of common databases.
+var (
+	SQLite   = Dialect{"TEXT", "BLOB", question}
+	MySQL    = Dialect{"LONGTEXT", "LONGBLOB", question}
+	Postgres = Dialect{"TEXT", "BYTEA", func(n int) string { return "$" + strconv.Itoa(n) }}
+)
+
+// question writes every parameter as a question mark.
+func question(int) string {
+	return "?"
+}
+
+// Store keeps the revisions of documents in a table of a database.
+type Store struct {
+	db    *sql.DB
+	d     Dialect
+	table string
+}
+
+// New returns a store keeping its revisions in the given table of db,
+// "delta_revisions" when empty.
+func New(db *sql.DB, d Dialect, table string) *Store {
+	if table == "" {
+		table = "delta_revisions"
+	}
+	return &Store{db: db, d: d, table: table}
+}
+
+// Schema returns the statement creating the table, for databases set up by
+// migrations rather than by Init. There's a row for every revision of every
+// document, holding its text for the first and the latest revision, and the
+// patch turning the revision before into it for the others.
+func (s *Store) Schema() string {
+	return "CREATE TABLE IF NOT EXISTS " + s.table + " (" +
+		"document VARCHAR(255) NOT NULL, " +
+		"revision INTEGER NOT NULL, " +
+		"text " + s.d.Text + ", " +
+		"patch " + s.d.Bytes + ", " +
+		"PRIMARY KEY (document, revision))"
+}
+
+// Init creates the table, if it isn't there yet.
+func (s *Store) Init(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, s.Schema())
+	return err
+}
+
+// query writes the placeholders of the query, given as question marks, the
+// way the database wants them.
+func (s *Store) query(q string) string {
+	var b []byte
+	n := 0
+	for i := 0; i < len(q); i++ {
+		if q[i] == '?' {
+			n++
+			b = append(b, s.d.Placeholder(n)...)
+			continue
+		}
+		b = append(b, q[i])
+	}
+	return string(b)
+}
+
+// Put adds a revision of the document and returns its number, starting
+// with 0 for the first one. Callers must not put revisions of the same
+// document concurrently: both would take the number after the latest one,
+// and all but one of them fail on the primary key.
+func (s *Store) Put(ctx context.Context, document, text string) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var n int
+	var head string
+	err = tx.QueryRowContext(ctx, s.query("SELECT revision, text FROM "+s.table+
+		" WHERE document = ? AND revision = (SELECT MAX(revision) FROM "+s.table+" WHERE document = ?)"),
+		document, document).Scan(&n, &head)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		n = -1
+	case err != nil:
+		return 0, err
+	}
+
+	var patch []byte
+	if n >= 0 {
+		if patch, err = delta.MakePatch(head, text).MarshalBinary(); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, s.query("INSERT INTO "+s.table+
+		" (document, revision, text, patch) VALUES (?, ?, ?, ?)"),
+		document, n+1, text, patch); err != nil {
+		return 0, err
+	}
+
+	// The text of the revision which was the latest isn't needed any
+	// more, unless it's the first one.
+	if n > 0 {
+		if _, err := tx.ExecContext(ctx, s.query("UPDATE "+s.table+
+			" SET text = NULL WHERE document = ? AND revision = ?"), document, n); err != nil {
+			return 0, err
+		}
+	}
+	return n + 1, tx.Commit()
+}
+
+// Len returns the number of revisions of the document, 0 if there are none.
+func (s *Store) Len(ctx context.Context, document string) (int, error) {
+	var n int
+	err := s.db.QueryRowContext(ctx, s.query("SELECT COUNT(*) FROM "+s.table+
+		" WHERE document = ?"), document).Scan(&n)
+	return n, err
+}
+
+// Revision returns the revision of the document with the given number, by
+// applying the patches up to it to the first revision.
+func (s *Store) Revision(ctx context.Context, document string, n int) (string, error) {
+	if n < 0 {
+		return "", ErrNotFound
+	}
+	rows, err := s.db.QueryContext(ctx, s.query("SELECT revision, text, patch FROM "+s.table+
+		" WHERE document = ? AND revision <= ? ORDER BY revision"), document, n)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var text string
+	found := -1
+	for rows.Next() {
+		var rev int
+		var full sql.NullString
+		var patch []byte
+		if err := rows.Scan(&rev, &full, &patch); err != nil {
+			return "", err
+		}
+		if rev != found+1 {
+			return "", fmt.Errorf("deltastore: revision %d of %q is missing", found+1, document)
+		}
+		found = rev
+
+		if full.Valid {
+			text = full.String
+			continue
+		}
+		var p delta.Patch
+		if err := p.UnmarshalBinary(patch); err != nil {
+			return "", err
+		}
+		if text, err = p.Apply(text); err != nil {
+			return "", err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	if found != n {
+		return "", ErrNotFound
+	}
+	return text, nil
+}
@@ -0,0 +1,179 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// memory is a database/sql driver keeping the rows of the store in memory,
+// understanding just the statements the store runs. Databases are shared by
+// name, so every connection to one sees the same rows.
+type memory struct {
+	mu  sync.Mutex
+	dbs map[string]map[string]*row
+}
+
+// row is a revision of a document.
+type row struct {
+	document string
+	revision int64
+	text     any
+	patch    []byte
+}
+
+func init() {
+	sql.Register("memory", &memory{dbs: make(map[string]map[string]*row)})
+}
+
+func (m *memory) Open(name string) (driver.Conn, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.dbs[name] == nil {
+		m.dbs[name] = make(map[string]*row)
+	}
+	return &conn{m: m, rows: m.dbs[name]}, nil
+}
+
+type conn struct {
+	m    *memory
+	rows map[string]*row
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) { return &stmt{c, query}, nil }
+func (c *conn) Close() error                              { return nil }
+func (c *conn) Begin() (driver.Tx, error)                 { return c, nil }
+func (c *conn) Commit() error                             { return nil }
+func (c *conn) Rollback() error                           { return nil }
+
+type stmt struct {
+	c     *conn
+	query string
+}
+
+func (s *stmt) Close() error  { return nil }
+func (s *stmt) NumInput() int { return -1 }
+
+// key is the key of a row in the database.
+func key(document string, revision int64) string {
+	return document + "\x00" + strconv.FormatInt(revision, 10)
+}
+
+// revisions returns the revisions of the document, in order.
+func (s *stmt) revisions(document string) []*row {
+	var rs []*row
+	for _, r := range s.c.rows {
+		if r.document == document {
+			rs = append(rs, r)
+		}
+	}
+	sort.Slice(rs, func(i, j int) bool { return rs[i].revision < rs[j].revision })
+	return rs
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.c.m.mu.Lock()
+	defer s.c.m.mu.Unlock()
+	switch {
+	case strings.HasPrefix(s.query, "CREATE TABLE"):
+	case strings.HasPrefix(s.query, "INSERT"):
+		r := &row{document: args[0].(string), revision: args[1].(int64), text: args[2]}
+		r.patch, _ = args[3].([]byte)
+		if s.c.rows[key(r.document, r.revision)] != nil {
+			return nil, errors.New("duplicate primary key")
+		}
+		s.c.rows[key(r.document, r.revision)] = r
+	case strings.HasPrefix(s.query, "UPDATE"):
+		s.c.rows[key(args[0].(string), args[1].(int64))].text = nil
+	default:
+		return nil, errors.New("unexpected statement " + s.query)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.c.m.mu.Lock()
+	defer s.c.m.mu.Unlock()
+	rs := s.revisions(args[0].(string))
+	switch {
+	case strings.HasPrefix(s.query, "SELECT COUNT(*)"):
+		return &rows{columns: []string{"count"}, values: [][]driver.Value{{int64(len(rs))}}}, nil
+	case strings.HasPrefix(s.query, "SELECT revision, text FROM"):
+		out := &rows{columns: []string{"revision", "text"}}
+		if len(rs) > 0 {
+			last := rs[len(rs)-1]
+			out.values = append(out.values, []driver.Value{last.revision, last.text})
+		}
+		return out, nil
+	case strings.HasPrefix(s.query, "SELECT revision, text, patch FROM"):
+		out := &rows{columns: []string{"revision", "text", "patch"}}
+		for _, r := range rs {
+			if r.revision <= args[1].(int64) {
+				out.values = append(out.values, []driver.Value{r.revision, r.text, r.patch})
+			}
+		}
+		return out, nil
+	}
+	return nil, errors.New("unexpected query " + s.query)
+}
+
+type rows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *rows) Columns() []string { return r.columns }
+func (r *rows) Close() error      { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func TestStore(t *testing.T) {
+	db, err := sql.Open("memory", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	s := New(db, SQLite, "")
+	if err := s.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	texts := []string{"hello world", "hello earth", "goodbye earth", "goodbye cruel earth"}
+	for i, text := range texts {
+		if n, err := s.Put(ctx, "readme", text); n != i || err != nil {
+			t.Fatalf("Put(%q) = %d, %v, want %d", text, n, err, i)
+		}
+	}
+	s.Put(ctx, "other", "unrelated")
+
+	if n, err := s.Len(ctx, "readme"); n != len(texts) || err != nil {
+		t.Errorf("Len = %d, %v, want %d", n, err, len(texts))
+	}
+	for i, want := range texts {
+		if got, err := s.Revision(ctx, "readme", i); got != want || err != nil {
+			t.Errorf("Revision(%d) = %q, %v, want %q", i, got, err, want)
+		}
+	}
+	if _, err := s.Revision(ctx, "readme", len(texts)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Revision(%d) = %v, want ErrNotFound", len(texts), err)
+	}
+	if n, err := s.Len(ctx, "missing"); n != 0 || err != nil {
+		t.Errorf("Len of a missing document = %d, %v, want 0", n, err)
+	}
+}
@@ -0,0 +1,213 @@
+// Deltastruct compares two Go values field by field using reflection, and
+// reports what changed along with the path to it. String fields are compared
+// with delta, so their changes show up word by word. Handy for audit logging
+// of configuration objects.
+//
+// Examples:
+//
+//	deltastruct.Diff(Config{Name: "hello world", Port: 80}, Config{Name: "hello earth", Port: 8080})
+//		// []Change{
+//		// 	{Path: "Name", Old: "hello world", New: "hello earth", Diff: "hello ---world--- +++earth+++"},
+//		// 	{Path: "Port", Old: 80, New: 8080},
+//		// }
+package deltastruct
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/nkrs/delta"
+)
+
+// Change is a single difference between the two values. Old or New is nil
+// when the value is missing on that side, such as an element appended to a
+// slice or a key deleted from a map. Diff holds the plain text delta of the
+// two when both of them are strings.
+type Change struct {
+	Path string
+	Old  interface{}
+	New  interface{}
+	Diff string
+}
+
+// String returns the change in a form suitable for logging.
+func (c Change) String() string {
+	if c.Diff != "" {
+		return c.Path + ": " + c.Diff
+	}
+	return fmt.Sprintf("%s: %v -> %v", c.Path, c.Old, c.New)
+}
+
+// Diff compares the two values and returns the changes between them, in the
+// order the fields are declared in. Maps are walked in the order of their
+// keys. Unexported fields are skipped, as are the fields tagged with
+// `delta:"-"`. Options are passed on to delta when diffing strings.
+func Diff(prev, curr interface{}, opts ...delta.Option) []Change {
+	w := &walker{opts: opts, seen: make(map[visit]bool)}
+	w.walk("", reflect.ValueOf(prev), reflect.ValueOf(curr))
+	return w.changes
+}
+
+// visit is a pair of pointers already being compared, so cyclic values don't
+// send the walker around in circles.
+type visit struct {
+	prev, curr uintptr
+	typ        reflect.Type
+}
+
+type walker struct {
+	opts    []delta.Option
+	seen    map[visit]bool
+	changes []Change
+}
+
+// walk compares the two values found on the given path.
+func (w *walker) walk(path string, prev, curr reflect.Value) {
+	if !prev.IsValid() || !curr.IsValid() || prev.Type() != curr.Type() {
+		if prev.IsValid() || curr.IsValid() {
+			w.report(path, prev, curr)
+		}
+		return
+	}
+
+	switch prev.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if prev.IsNil() || curr.IsNil() {
+			if prev.IsNil() != curr.IsNil() {
+				w.report(path, prev, curr)
+			}
+			return
+		}
+		if prev.Kind() == reflect.Ptr {
+			v := visit{prev.Pointer(), curr.Pointer(), prev.Type()}
+			if w.seen[v] {
+				return
+			}
+			w.seen[v] = true
+		}
+		w.walk(path, prev.Elem(), curr.Elem())
+
+	case reflect.Struct:
+		if equal, ok := equaler(prev, curr); ok {
+			if !equal {
+				w.report(path, prev, curr)
+			}
+			return
+		}
+
+		t := prev.Type()
+		fields := 0
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" || f.Tag.Get("delta") == "-" {
+				continue
+			}
+			fields++
+			w.walk(join(path, f.Name), prev.Field(i), curr.Field(i))
+		}
+
+		// Structs hiding everything in unexported fields can only
+		// be compared as a whole.
+		if fields == 0 && !reflect.DeepEqual(prev.Interface(), curr.Interface()) {
+			w.report(path, prev, curr)
+		}
+
+	case reflect.Map:
+		keys := make(map[string]reflect.Value)
+		for _, k := range prev.MapKeys() {
+			keys[fmt.Sprintf("%#v", k.Interface())] = k
+		}
+		for _, k := range curr.MapKeys() {
+			keys[fmt.Sprintf("%#v", k.Interface())] = k
+		}
+
+		names := make([]string, 0, len(keys))
+		for name := range keys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			k := keys[name]
+			w.walk(path+"["+name+"]", prev.MapIndex(k), curr.MapIndex(k))
+		}
+
+	case reflect.Slice, reflect.Array:
+		if prev.Kind() == reflect.Slice && prev.Type().Elem().Kind() == reflect.Uint8 {
+			if string(prev.Bytes()) != string(curr.Bytes()) {
+				w.report(path, prev, curr)
+			}
+			return
+		}
+
+		for i := 0; i < prev.Len() || i < curr.Len(); i++ {
+			var p, c reflect.Value
+			if i < prev.Len() {
+				p = prev.Index(i)
+			}
+			if i < curr.Len() {
+				c = curr.Index(i)
+			}
+			w.walk(fmt.Sprintf("%s[%d]", path, i), p, c)
+		}
+
+	case reflect.String:
+		if prev.String() != curr.String() {
+			w.report(path, prev, curr)
+		}
+
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		if prev.Pointer() != curr.Pointer() {
+			w.report(path, prev, curr)
+		}
+
+	default:
+		if prev.Interface() != curr.Interface() {
+			w.report(path, prev, curr)
+		}
+	}
+}
+
+// report records a change between the two values, either of which may be
+// missing.
+func (w *walker) report(path string, prev, curr reflect.Value) {
+	c := Change{Path: path, Old: value(prev), New: value(curr)}
+
+	p, pok := c.Old.(string)
+	n, nok := c.New.(string)
+	if pok && nok {
+		c.Diff = delta.Calculate(p, n, true, w.opts...)
+	}
+
+	w.changes = append(w.changes, c)
+}
+
+// equaler compares the values using their Equal method, for types like
+// time.Time which know best when they are equal.
+func equaler(prev, curr reflect.Value) (equal, ok bool) {
+	m := prev.MethodByName("Equal")
+	if !m.IsValid() || m.Type().NumIn() != 1 || m.Type().In(0) != prev.Type() ||
+		m.Type().NumOut() != 1 || m.Type().Out(0).Kind() != reflect.Bool {
+		return false, false
+	}
+	return m.Call([]reflect.Value{curr})[0].Bool(), true
+}
+
+// value returns the interface of the value, or nil when it's missing or
+// can't be had.
+func value(v reflect.Value) interface{} {
+	if !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+	return v.Interface()
+}
+
+// join adds a field name to the path.
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return strings.Join([]string{path, name}, ".")
+}
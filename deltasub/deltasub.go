@@ -0,0 +1,232 @@
+// Deltasub compares subtitle files in SubRip (SRT) or WebVTT format cue by
+// cue. Cues are aligned by their timecodes, the captions of each are
+// compared word by word, and changes to the timing are told apart from
+// changes to the text, for checking the translations of subtitles.
+//
+// Examples:
+//
+//	deltasub.Compare("1\n00:00:01,000 --> 00:00:03,000\nHello world\n", "1\n00:00:01,500 --> 00:00:03,000\nHello earth\n", true)
+//		// []Change{{Prev: &Cue{...}, Curr: &Cue{...}, Timing: true, Diff: "Hello ---world--- +++earth+++"}}, nil
+package deltasub
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nkrs/delta"
+)
+
+// Cue is a caption shown from Start to End. ID is the number of the cue in
+// SubRip files, and its identifier, if any, in WebVTT ones.
+type Cue struct {
+	ID         string
+	Start, End time.Duration
+	Text       string
+}
+
+// String returns the timecodes and text of the cue.
+func (c Cue) String() string {
+	return timecode(c.Start) + " --> " + timecode(c.End) + " " + c.Text
+}
+
+// Change is a cue which changed. Prev is nil for cues which were added,
+// and Curr for those which were removed. Timing tells whether the cue is
+// shown at another time, and Diff holds the delta of the texts, which is
+// empty when they're the same.
+type Change struct {
+	Prev, Curr *Cue
+	Timing     bool
+	Diff       string
+}
+
+// String returns the change in a form suitable for logging.
+func (c Change) String() string {
+	switch {
+	case c.Prev == nil:
+		return "added " + c.Curr.String()
+	case c.Curr == nil:
+		return "removed " + c.Prev.String()
+	}
+	s := timecode(c.Curr.Start) + " --> " + timecode(c.Curr.End)
+	if c.Timing {
+		s = timecode(c.Prev.Start) + " --> " + timecode(c.Prev.End) + " moved to " + s
+	}
+	if c.Diff != "" {
+		s += " " + c.Diff
+	}
+	return s
+}
+
+// Compare parses the two subtitle files and returns the cues which
+// changed, in order, with the deltas of their texts as HTML or plain text
+// like delta.Calculate. Cues are matched by their timecodes; of cues which
+// don't match any, those in the same place are taken to be the same cue
+// shown at another time. Options are passed on to delta.
+func Compare(prev, curr string, plaintext bool, opts ...delta.Option) ([]Change, error) {
+	pc, err := Parse(prev)
+	if err != nil {
+		return nil, err
+	}
+	cc, err := Parse(curr)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	pair := func(p, c *Cue) {
+		ch := Change{Prev: p, Curr: c}
+		switch {
+		case p == nil:
+			ch.Diff = delta.CalculateTokens(nil, []string{c.Text}, plaintext, opts...)
+		case c == nil:
+			ch.Diff = delta.CalculateTokens([]string{p.Text}, nil, plaintext, opts...)
+		default:
+			ch.Timing = p.Start != c.Start || p.End != c.End
+			if p.Text != c.Text {
+				ch.Diff = delta.Calculate(p.Text, c.Text, plaintext, opts...)
+			}
+			if !ch.Timing && ch.Diff == "" {
+				return
+			}
+		}
+		changes = append(changes, ch)
+	}
+
+	i, j := 0, 0
+	edits := delta.DiffSlices(timings(pc), timings(cc))
+	for k := 0; k < len(edits); k++ {
+		e := edits[k]
+		switch e.Op {
+		case delta.Equal:
+			for range e.Items {
+				pair(&pc[i], &cc[j])
+				i, j = i+1, j+1
+			}
+		case delta.Delete:
+			inserted := 0
+			if k+1 < len(edits) && edits[k+1].Op == delta.Insert {
+				inserted = len(edits[k+1].Items)
+				k++
+			}
+			for n := 0; n < max(len(e.Items), inserted); n++ {
+				switch {
+				case n >= len(e.Items):
+					pair(nil, &cc[j])
+					j++
+				case n >= inserted:
+					pair(&pc[i], nil)
+					i++
+				default:
+					pair(&pc[i], &cc[j])
+					i, j = i+1, j+1
+				}
+			}
+		case delta.Insert:
+			for range e.Items {
+				pair(nil, &cc[j])
+				j++
+			}
+		}
+	}
+	return changes, nil
+}
+
+// timing is when a cue is shown, which cues are matched by.
+type timing struct {
+	start, end time.Duration
+}
+
+// timings returns when each of the cues is shown.
+func timings(cues []Cue) []timing {
+	ts := make([]timing, len(cues))
+	for i, c := range cues {
+		ts[i] = timing{c.Start, c.End}
+	}
+	return ts
+}
+
+// Parse reads the cues of a subtitle file, in WebVTT format when it starts
+// with "WEBVTT" and in SubRip format otherwise. Styles, regions and notes of
+// WebVTT files are left out, as are the settings of the cues.
+func Parse(text string) ([]Cue, error) {
+	text = strings.TrimPrefix(text, "\ufeff")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	vtt := strings.HasPrefix(text, "WEBVTT")
+
+	var cues []Cue
+	line := 1
+	for n, block := range strings.Split(text, "\n\n") {
+		start := line + len(block) - len(strings.TrimLeft(block, "\n"))
+		line += strings.Count(block, "\n") + 2
+		lines := strings.Split(strings.Trim(block, "\n"), "\n")
+		if lines[0] == "" || vtt && (n == 0 || isMeta(lines[0])) {
+			continue
+		}
+
+		c := Cue{}
+		if !strings.Contains(lines[0], "-->") {
+			c.ID, lines = strings.TrimSpace(lines[0]), lines[1:]
+			start++
+		}
+		if len(lines) == 0 || !strings.Contains(lines[0], "-->") {
+			return nil, fmt.Errorf("deltasub: line %d: cue has no timecodes", start)
+		}
+		from, to, _ := strings.Cut(lines[0], "-->")
+		if fields := strings.Fields(to); len(fields) > 0 {
+			to = fields[0]
+		}
+		var err error
+		if c.Start, err = parseTimecode(from); err != nil {
+			return nil, fmt.Errorf("deltasub: line %d: %w", start, err)
+		}
+		if c.End, err = parseTimecode(to); err != nil {
+			return nil, fmt.Errorf("deltasub: line %d: %w", start, err)
+		}
+		c.Text = strings.Join(lines[1:], "\n")
+		cues = append(cues, c)
+	}
+	return cues, nil
+}
+
+// isMeta tells whether a WebVTT block starting with the line holds no cue.
+func isMeta(line string) bool {
+	for _, kind := range []string{"NOTE", "STYLE", "REGION"} {
+		if line == kind || strings.HasPrefix(line, kind+" ") || strings.HasPrefix(line, kind+"\t") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTimecode parses a timecode such as "01:02:03,456" or "02:03.456".
+// Hours may be left out, and milliseconds follow either a comma or a dot.
+func parseTimecode(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	clock, frac, ok := strings.Cut(strings.Replace(s, ",", ".", 1), ".")
+	parts := strings.Split(clock, ":")
+	if !ok || len(frac) != 3 || len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("bad timecode %q", s)
+	}
+
+	var d time.Duration
+	for _, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("bad timecode %q", s)
+		}
+		d = d*60 + time.Duration(n)
+	}
+	ms, err := strconv.Atoi(frac)
+	if err != nil || ms < 0 {
+		return 0, fmt.Errorf("bad timecode %q", s)
+	}
+	return d*time.Second + time.Duration(ms)*time.Millisecond, nil
+}
+
+// timecode formats the time as a SubRip timecode.
+func timecode(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d,%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
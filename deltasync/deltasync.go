@@ -0,0 +1,442 @@
+// Deltasync syncs large files where only small parts changed, the way rsync
+// does. The side holding the old file describes it as a signature, a list of
+// checksums of its blocks. The side holding the new file then only sends a
+// delta: references to the blocks the other side already has, and the bytes
+// it doesn't. Neither side ever needs both files.
+//
+// Examples:
+//
+//	sig, _ := deltasync.NewSignature(oldFile, deltasync.DefaultBlockSize)
+//	deltasync.Delta(&patch, sig, newFile)
+//	deltasync.Patch(restored, oldFile, &patch)
+//		// restored now holds what newFile does
+package deltasync
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+)
+
+// DefaultBlockSize is a good block size for files in the megabytes. Smaller
+// blocks find more of the old file again, but make bigger signatures.
+const DefaultBlockSize = 2048
+
+var (
+	// ErrCorrupt is returned when a signature or delta can't be read.
+	ErrCorrupt = errors.New("deltasync: corrupt data")
+
+	// ErrChecksum is returned by Patch when the patched file doesn't come
+	// out as the one the delta was made from, which happens when it's
+	// applied to a different old file than the signature was made of.
+	ErrChecksum = errors.New("deltasync: checksum mismatch")
+)
+
+// Magic bytes starting signatures and deltas.
+var (
+	signatureMagic = []byte("DSYS\x01")
+	deltaMagic     = []byte("DSYD\x01")
+)
+
+// Operations of a delta.
+const (
+	opEnd byte = iota
+	opCopy
+	opLiteral
+)
+
+// maxLiteral is how many new bytes are held back before being written out.
+const maxLiteral = 1 << 16
+
+// Block is the checksums of a single block of the old file: a weak one which
+// is cheap to roll over the new file, and a strong one confirming a match.
+type Block struct {
+	Weak   uint32
+	Strong [sha256.Size]byte
+}
+
+// Signature describes the old file as the checksums of its blocks. All blocks
+// are BlockSize long, except for the last one which may be shorter.
+type Signature struct {
+	BlockSize int
+	Length    int64
+	Blocks    []Block
+}
+
+// NewSignature reads the old file and returns its signature.
+func NewSignature(r io.Reader, blockSize int) (*Signature, error) {
+	if blockSize <= 0 {
+		return nil, fmt.Errorf("deltasync: invalid block size %d", blockSize)
+	}
+
+	s := &Signature{BlockSize: blockSize}
+	buf := make([]byte, blockSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			s.Length += int64(n)
+			s.Blocks = append(s.Blocks, Block{weak(buf[:n]), sha256.Sum256(buf[:n])})
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return s, nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// WriteTo writes the signature out, to be sent to the side with the new
+// file.
+func (s *Signature) WriteTo(w io.Writer) (int64, error) {
+	var b []byte
+	b = append(b, signatureMagic...)
+	b = binary.AppendUvarint(b, uint64(s.BlockSize))
+	b = binary.AppendUvarint(b, uint64(s.Length))
+	b = binary.AppendUvarint(b, uint64(len(s.Blocks)))
+	for _, block := range s.Blocks {
+		b = binary.BigEndian.AppendUint32(b, block.Weak)
+		b = append(b, block.Strong[:]...)
+	}
+
+	n, err := w.Write(b)
+	return int64(n), err
+}
+
+// ReadSignature reads a signature written by WriteTo.
+func ReadSignature(r io.Reader) (*Signature, error) {
+	br := bufio.NewReader(r)
+	if err := expect(br, signatureMagic); err != nil {
+		return nil, err
+	}
+
+	var v [3]uint64
+	for i := range v {
+		var err error
+		if v[i], err = binary.ReadUvarint(br); err != nil {
+			return nil, ErrCorrupt
+		}
+	}
+	if v[0] == 0 || v[0] > 1<<30 || v[1] > math.MaxInt64 || v[2] != v[1]/v[0]+min(v[1]%v[0], 1) {
+		return nil, ErrCorrupt
+	}
+
+	// The count of blocks comes from the signature itself, so they're
+	// added as they're read rather than allocated up front.
+	s := &Signature{BlockSize: int(v[0]), Length: int64(v[1])}
+	for range v[2] {
+		var b [4 + sha256.Size]byte
+		if _, err := io.ReadFull(br, b[:]); err != nil {
+			return nil, ErrCorrupt
+		}
+		block := Block{Weak: binary.BigEndian.Uint32(b[:4])}
+		copy(block.Strong[:], b[4:])
+		s.Blocks = append(s.Blocks, block)
+	}
+	return s, nil
+}
+
+// size returns the length of the given block.
+func (s *Signature) size(block int) int {
+	if block == len(s.Blocks)-1 && s.Length%int64(s.BlockSize) != 0 {
+		return int(s.Length % int64(s.BlockSize))
+	}
+	return s.BlockSize
+}
+
+// Delta reads the new file and writes out the delta turning the old file,
+// known only by its signature, into it. The new file is streamed through,
+// so it can be of any size.
+func Delta(w io.Writer, sig *Signature, r io.Reader) error {
+	if sig.BlockSize <= 0 {
+		return fmt.Errorf("deltasync: invalid block size %d", sig.BlockSize)
+	}
+
+	bw := bufio.NewWriter(w)
+	e := &encoder{w: bw, sum: sha256.New(), run: -1}
+
+	head := append([]byte{}, deltaMagic...)
+	head = binary.AppendUvarint(head, uint64(sig.BlockSize))
+	head = binary.AppendUvarint(head, uint64(sig.Length))
+	bw.Write(head)
+
+	blocks := make(map[uint32][]int)
+	for i, b := range sig.Blocks {
+		blocks[b.Weak] = append(blocks[b.Weak], i)
+	}
+
+	// The buffer holds the new bytes not yet written out, followed by
+	// the window being checked against the blocks.
+	br := bufio.NewReaderSize(r, 4*sig.BlockSize)
+	var buf []byte
+	start, eof := 0, false
+	var sum rolling
+
+	for {
+		for !eof && len(buf)-start < sig.BlockSize {
+			c, err := br.ReadByte()
+			if err == io.EOF {
+				eof = true
+				break
+			} else if err != nil {
+				return err
+			}
+			buf = append(buf, c)
+			if len(buf)-start == sig.BlockSize {
+				sum = newRolling(buf[start:])
+			}
+		}
+
+		window := buf[start:]
+		if len(window) == 0 {
+			break
+		}
+
+		// Only the last block of the old file can be shorter, so it's
+		// also the only one to look for at the end of the new one.
+		var weakSum uint32
+		if len(window) == sig.BlockSize {
+			weakSum = sum.value()
+		} else {
+			weakSum = weak(window)
+		}
+
+		if block, ok := find(sig, blocks, weakSum, window); ok {
+			if err := e.literal(buf[:start]); err != nil {
+				return err
+			}
+			if err := e.copy(block, window); err != nil {
+				return err
+			}
+			buf, start = buf[:0], 0
+			continue
+		}
+
+		// No match, so the first byte of the window is new and the
+		// window moves on by one.
+		if len(window) == sig.BlockSize && !eof {
+			c, err := br.ReadByte()
+			if err == nil {
+				buf = append(buf, c)
+				sum.roll(window[0], c)
+			} else if err == io.EOF {
+				eof = true
+			} else {
+				return err
+			}
+		}
+		start++
+
+		if start >= maxLiteral {
+			if err := e.literal(buf[:start]); err != nil {
+				return err
+			}
+			buf, start = append(buf[:0], buf[start:]...), 0
+		}
+	}
+
+	if err := e.literal(buf); err != nil {
+		return err
+	}
+	if err := e.flush(); err != nil {
+		return err
+	}
+	bw.WriteByte(opEnd)
+	bw.Write(e.sum.Sum(nil))
+	return bw.Flush()
+}
+
+// find looks for a block of the old file matching the window.
+func find(sig *Signature, blocks map[uint32][]int, weakSum uint32, window []byte) (int, bool) {
+	candidates := blocks[weakSum]
+	if len(candidates) == 0 {
+		return 0, false
+	}
+
+	strong := sha256.Sum256(window)
+	for _, i := range candidates {
+		if sig.size(i) == len(window) && sig.Blocks[i].Strong == strong {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// encoder writes out the operations of a delta, joining copies of
+// consecutive blocks into one.
+type encoder struct {
+	w          *bufio.Writer
+	sum        hash.Hash
+	run, count int
+}
+
+// copy records a copy of the given block, which holds data.
+func (e *encoder) copy(block int, data []byte) error {
+	e.sum.Write(data)
+	if e.run >= 0 && e.run+e.count == block {
+		e.count++
+		return nil
+	}
+	if err := e.flush(); err != nil {
+		return err
+	}
+	e.run, e.count = block, 1
+	return nil
+}
+
+// flush writes out the pending run of copies.
+func (e *encoder) flush() error {
+	if e.run < 0 {
+		return nil
+	}
+	b := []byte{opCopy}
+	b = binary.AppendUvarint(b, uint64(e.run))
+	b = binary.AppendUvarint(b, uint64(e.count))
+	e.run = -1
+	_, err := e.w.Write(b)
+	return err
+}
+
+// literal writes out new bytes.
+func (e *encoder) literal(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if err := e.flush(); err != nil {
+		return err
+	}
+	e.sum.Write(data)
+	b := []byte{opLiteral}
+	b = binary.AppendUvarint(b, uint64(len(data)))
+	e.w.Write(b)
+	_, err := e.w.Write(data)
+	return err
+}
+
+// Patch applies the delta to the old file, writing the new one to w. The
+// result is checked against the checksum of the new file stored in the
+// delta, so applying a delta to the wrong file doesn't go unnoticed, though
+// w will already have been written to by then.
+func Patch(w io.Writer, base io.ReaderAt, delta io.Reader) error {
+	br := bufio.NewReader(delta)
+	if err := expect(br, deltaMagic); err != nil {
+		return err
+	}
+	blockSize, err := binary.ReadUvarint(br)
+	if err != nil || blockSize == 0 || blockSize > 1<<30 {
+		return ErrCorrupt
+	}
+	length, err := binary.ReadUvarint(br)
+	if err != nil {
+		return ErrCorrupt
+	}
+
+	sum := sha256.New()
+	out := io.MultiWriter(w, sum)
+	buf := make([]byte, blockSize)
+
+	for {
+		op, err := br.ReadByte()
+		if err != nil {
+			return ErrCorrupt
+		}
+
+		switch op {
+		case opEnd:
+			var want [sha256.Size]byte
+			if _, err := io.ReadFull(br, want[:]); err != nil {
+				return ErrCorrupt
+			}
+			if !bytes.Equal(sum.Sum(nil), want[:]) {
+				return ErrChecksum
+			}
+			return nil
+
+		case opCopy:
+			block, err1 := binary.ReadUvarint(br)
+			count, err2 := binary.ReadUvarint(br)
+			if err1 != nil || err2 != nil || block+count > (length+blockSize-1)/blockSize {
+				return ErrCorrupt
+			}
+			for i := block; i < block+count; i++ {
+				off := i * blockSize
+				size := min(blockSize, length-off)
+
+				// Readers may or may not say EOF when reading up
+				// to the very end, which is fine either way.
+				n, err := base.ReadAt(buf[:size], int64(off))
+				if uint64(n) == size {
+					err = nil
+				}
+				if err != nil {
+					return err
+				}
+				if _, err := out.Write(buf[:n]); err != nil {
+					return err
+				}
+			}
+
+		case opLiteral:
+			n, err := binary.ReadUvarint(br)
+			if err != nil {
+				return ErrCorrupt
+			}
+			if _, err := io.CopyN(out, br, int64(n)); err != nil {
+				if err == io.EOF {
+					return ErrCorrupt
+				}
+				return err
+			}
+
+		default:
+			return ErrCorrupt
+		}
+	}
+}
+
+// expect reads and checks the magic bytes.
+func expect(r io.Reader, magic []byte) error {
+	b := make([]byte, len(magic))
+	if _, err := io.ReadFull(r, b); err != nil || !bytes.Equal(b, magic) {
+		return ErrCorrupt
+	}
+	return nil
+}
+
+// rolling is the weak checksum of rsync, which can be moved along the data
+// a byte at a time without going over the whole window again.
+type rolling struct {
+	a, b uint32
+	n    uint32
+}
+
+// newRolling computes the checksum of the window.
+func newRolling(window []byte) rolling {
+	r := rolling{n: uint32(len(window))}
+	for i, c := range window {
+		r.a += uint32(c)
+		r.b += uint32(len(window)-i) * uint32(c)
+	}
+	return r
+}
+
+// roll moves the window a byte further, dropping out and taking in.
+func (r *rolling) roll(out, in byte) {
+	r.a += uint32(in) - uint32(out)
+	r.b += r.a - r.n*uint32(out)
+}
+
+// value returns the checksum.
+func (r rolling) value() uint32 {
+	return r.a&0xffff | r.b<<16
+}
+
+// weak computes the weak checksum of a block in one go.
+func weak(block []byte) uint32 {
+	return newRolling(block).value()
+}
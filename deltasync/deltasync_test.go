@@ -0,0 +1,40 @@
+package deltasync
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSignature(t *testing.T) {
+	sig, err := NewSignature(bytes.NewReader([]byte("hello, world")), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	sig.WriteTo(&b)
+	got, err := ReadSignature(&b)
+	if err != nil || got.BlockSize != 4 || got.Length != 12 || len(got.Blocks) != 3 || got.Blocks[2] != sig.Blocks[2] {
+		t.Errorf("ReadSignature = %+v, %v, want %+v", got, err, sig)
+	}
+}
+
+func TestReadSignatureCorrupt(t *testing.T) {
+	tests := map[string][]byte{
+		// A signature of 2^56 blocks of a byte, with none of them there.
+		"huge count":  append([]byte("DSYS\x01\x01\x80\x80\x80\x80\x80\x80\x80\x80\x01"), 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x01),
+		"wrong count": []byte("DSYS\x01\x04\x0c\x02"),
+	}
+	for name, sig := range tests {
+		if _, err := ReadSignature(bytes.NewReader(sig)); !errors.Is(err, ErrCorrupt) {
+			t.Errorf("%s: ReadSignature = %v, want ErrCorrupt", name, err)
+		}
+	}
+}
+
+func TestDeltaBlockSize(t *testing.T) {
+	var b bytes.Buffer
+	if err := Delta(&b, &Signature{}, bytes.NewReader([]byte("hello"))); err == nil {
+		t.Errorf("Delta with a block size of 0 = nil, want an error")
+	}
+}
@@ -0,0 +1,328 @@
+// Deltaurl compares two URLs part by part: the scheme, user, host, every
+// segment of the path, every parameter of the query and the fragment each
+// on their own, with the values compared word by word by delta. URLs taken
+// from API logs read much better that way than diffed as one long token.
+//
+// Examples:
+//
+//	deltaurl.Diff("https://api.example.com/v1/users?sort=name", "https://api.example.com/v2/users?sort=last+name&limit=10")
+//		// []Change{
+//		// 	{Part: "path[0]", Old: "v1", New: "v2", Diff: "---v1--- +++v2+++"},
+//		// 	{Part: "query[sort]", Old: "name", New: "last name", Diff: "+++last+++ name"},
+//		// 	{Part: "query[limit]", New: "10", Diff: "+++10+++"},
+//		// }, nil
+//
+//	deltaurl.Calculate("https://api.example.com/v1/users?sort=name", "https://api.example.com/v2/users?sort=last+name&limit=10", true)
+//		// "https://api.example.com/---v1--- +++v2+++/users?sort=+++last+++ name&+++limit=10+++", nil
+package deltaurl
+
+import (
+	"html"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/nkrs/delta"
+)
+
+// Change is a part of the URL which changed. Part names it: "scheme",
+// "user", "host", "opaque" or "fragment", "path[i]" for the ith segment of
+// the path, and "query[key]" for a parameter, or "query[key][i]" when the
+// key is given more than once. Path segments are numbered as in the URL
+// they're taken from, the previous one unless they were inserted. Old or New
+// is empty when the part is missing on that side, and Diff holds the plain
+// text delta of the two. Values are compared decoded, so "last+name" and
+// "last%20name" are the same.
+type Change struct {
+	Part     string
+	Old, New string
+	Diff     string
+}
+
+// String returns the change in a form suitable for logging.
+func (c Change) String() string {
+	return c.Part + ": " + c.Diff
+}
+
+// Diff parses the two URLs and returns the changes between their parts, in
+// the order they come in the URL. Options are passed on to delta when
+// comparing the values.
+func Diff(prev, curr string, opts ...delta.Option) ([]Change, error) {
+	pieces, err := split(prev, curr)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	for _, p := range pieces {
+		if p.name == "" || p.present == [2]bool{true, true} && p.old == p.new {
+			continue
+		}
+		changes = append(changes, Change{
+			Part: p.name,
+			Old:  p.old,
+			New:  p.new,
+			Diff: delta.CalculateValues(p.old, p.new, true, opts...),
+		})
+	}
+	return changes, nil
+}
+
+// Calculate parses the two URLs and returns the current one with the
+// changes to every part marked up, as HTML or plain text like
+// delta.Calculate. The parts are shown decoded.
+func Calculate(prev, curr string, plaintext bool, opts ...delta.Option) (string, error) {
+	pieces, err := split(prev, curr)
+	if err != nil {
+		return "", err
+	}
+
+	escape := html.EscapeString
+	if plaintext {
+		escape = func(s string) string { return s }
+	}
+
+	var b strings.Builder
+	for _, p := range pieces {
+		switch {
+		case p.name == "":
+			b.WriteString(escape(p.label))
+		case p.present == [2]bool{true, true}:
+			b.WriteString(escape(p.label))
+			b.WriteString(delta.CalculateValues(p.old, p.new, plaintext, opts...))
+			b.WriteString(escape(p.suffix))
+		default:
+			var old, new string
+			if p.present[0] {
+				old = p.label + p.old + p.suffix
+			}
+			if p.present[1] {
+				new = p.label + p.new + p.suffix
+			}
+			b.WriteString(delta.CalculateValues(old, new, plaintext, opts...))
+		}
+	}
+	return b.String(), nil
+}
+
+// piece is a part of the URLs, or a separator between parts when it has no
+// name. The label goes before the value, such as the key of a parameter,
+// and the suffix after it; both belong to the part whenever it's only on
+// one side.
+type piece struct {
+	name          string
+	label, suffix string
+	old, new      string
+	present       [2]bool
+}
+
+// part returns the piece of a part, present on the sides it's not empty on.
+func part(name, old, new string) piece {
+	return piece{name: name, old: old, new: new, present: [2]bool{old != "", new != ""}}
+}
+
+// separator returns a piece which only separates parts.
+func separator(text string) piece {
+	return piece{label: text}
+}
+
+// split parses the URLs and splits them into their parts, aligned.
+func split(prev, curr string) ([]piece, error) {
+	p, err := url.Parse(prev)
+	if err != nil {
+		return nil, err
+	}
+	c, err := url.Parse(curr)
+	if err != nil {
+		return nil, err
+	}
+
+	var pieces []piece
+	if p.Scheme != "" || c.Scheme != "" {
+		pieces = append(pieces, part("scheme", p.Scheme, c.Scheme), separator(":"))
+	}
+	if p.Opaque != "" || c.Opaque != "" {
+		pieces = append(pieces, part("opaque", p.Opaque, c.Opaque))
+	}
+	if p.Host != "" || c.Host != "" || p.User != nil || c.User != nil {
+		pieces = append(pieces, separator("//"))
+		if p.User != nil || c.User != nil {
+			user := part("user", p.User.String(), c.User.String())
+			user.suffix = "@"
+			pieces = append(pieces, user)
+		}
+		pieces = append(pieces, part("host", p.Host, c.Host))
+	}
+	pieces = append(pieces, path(p.Path, c.Path)...)
+	pieces = append(pieces, query(p.RawQuery, c.RawQuery)...)
+	if p.Fragment != "" || c.Fragment != "" {
+		fragment := part("fragment", p.Fragment, c.Fragment)
+		if fragment.present == [2]bool{true, true} {
+			pieces = append(pieces, separator("#"))
+		} else {
+			fragment.label = "#"
+		}
+		pieces = append(pieces, fragment)
+	}
+	return pieces, nil
+}
+
+// path aligns the segments of the paths, pairing up the segments replaced
+// by others.
+func path(prev, curr string) []piece {
+	if prev == "" && curr == "" {
+		return nil
+	}
+	root := strings.HasPrefix(prev, "/") || strings.HasPrefix(curr, "/")
+	ps, cs := segments(prev), segments(curr)
+	if root && len(ps) == 0 && len(cs) == 0 {
+		return []piece{separator("/")}
+	}
+
+	var pieces []piece
+	add := func(p piece) {
+		if len(pieces) > 0 || root {
+			pieces = append(pieces, separator("/"))
+		}
+		pieces = append(pieces, p)
+	}
+	name := func(i int) string {
+		return "path[" + strconv.Itoa(i) + "]"
+	}
+
+	i, j := 0, 0
+	edits := delta.DiffSlices(ps, cs)
+	for k := 0; k < len(edits); k++ {
+		e := edits[k]
+		switch e.Op {
+		case delta.Equal:
+			for _, s := range e.Items {
+				add(piece{name: name(i), old: s, new: s, present: [2]bool{true, true}})
+				i, j = i+1, j+1
+			}
+		case delta.Delete:
+			var inserted []string
+			if k+1 < len(edits) && edits[k+1].Op == delta.Insert {
+				inserted = edits[k+1].Items
+				k++
+			}
+			for n := 0; n < max(len(e.Items), len(inserted)); n++ {
+				switch {
+				case n >= len(e.Items):
+					add(piece{name: name(j), new: inserted[n], present: [2]bool{false, true}})
+					j++
+				case n >= len(inserted):
+					add(piece{name: name(i), old: e.Items[n], present: [2]bool{true, false}})
+					i++
+				default:
+					add(piece{name: name(i), old: e.Items[n], new: inserted[n], present: [2]bool{true, true}})
+					i, j = i+1, j+1
+				}
+			}
+		case delta.Insert:
+			for _, s := range e.Items {
+				add(piece{name: name(j), new: s, present: [2]bool{false, true}})
+				j++
+			}
+		}
+	}
+	return pieces
+}
+
+// segments splits a path into its segments, leaving out the slash it
+// starts with.
+func segments(path string) []string {
+	if path = strings.TrimPrefix(path, "/"); path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// param is a parameter of a query. Bare is set for a key given without an
+// "=", such as "flag" in "?flag&sort=name".
+type param struct {
+	key, value string
+	bare       bool
+}
+
+// params parses a query into its parameters, in order, unlike url.Values.
+// Parameters which can't be decoded are taken as they are.
+func params(query string) []param {
+	var ps []param
+	for _, s := range strings.Split(query, "&") {
+		if s == "" {
+			continue
+		}
+		key, value, found := strings.Cut(s, "=")
+		if k, err := url.QueryUnescape(key); err == nil {
+			key = k
+		}
+		if v, err := url.QueryUnescape(value); err == nil {
+			value = v
+		}
+		ps = append(ps, param{key, value, !found})
+	}
+	return ps
+}
+
+// query pairs up the parameters of the queries by key, the ith one given
+// with a key in either with the ith one in the other. They're shown in the
+// order of the current query, followed by those only the previous one has.
+// When only one side has a query, the "?" goes with its first parameter.
+func query(prev, curr string) []piece {
+	ps, cs := params(prev), params(curr)
+	values := func(ps []param) map[string][]param {
+		m := make(map[string][]param)
+		for _, p := range ps {
+			m[p.key] = append(m[p.key], p)
+		}
+		return m
+	}
+	pv, cv := values(ps), values(cs)
+
+	var pieces []piece
+	add := func(key string, n int) {
+		name := "query[" + key + "]"
+		if len(pv[key]) > 1 || len(cv[key]) > 1 {
+			name += "[" + strconv.Itoa(n) + "]"
+		}
+		p := piece{name: name}
+		bare := true
+		if n < len(pv[key]) {
+			p.old, p.present[0] = pv[key][n].value, true
+			bare = bare && pv[key][n].bare
+		}
+		if n < len(cv[key]) {
+			p.new, p.present[1] = cv[key][n].value, true
+			bare = bare && cv[key][n].bare
+		}
+		p.label = key
+		if !bare {
+			p.label += "="
+		}
+
+		sep := "&"
+		if len(pieces) == 0 {
+			sep = "?"
+		}
+		if sep == "?" && (len(ps) == 0 || len(cs) == 0) {
+			p.label = sep + p.label
+			pieces = append(pieces, p)
+		} else {
+			pieces = append(pieces, separator(sep), p)
+		}
+	}
+
+	seen := make(map[string]int)
+	for _, c := range cs {
+		add(c.key, seen[c.key])
+		seen[c.key]++
+	}
+	for _, p := range ps {
+		if seen[p.key] < len(pv[p.key]) {
+			add(p.key, seen[p.key])
+			seen[p.key]++
+		}
+	}
+	return pieces
+}
@@ -0,0 +1,21 @@
+package deltaurl
+
+import "testing"
+
+func TestCalculate(t *testing.T) {
+	tests := []struct {
+		prev, curr string
+		want       string
+	}{
+		{"https://a.com/x?flag", "https://a.com/x", "https://a.com/x---?flag---"},
+		{"https://a.com/x", "https://a.com/x?flag&b=1", "https://a.com/x+++?flag+++&+++b=1+++"},
+		{"https://a.com/x?flag", "https://a.com/x?flag=1", "https://a.com/x?flag=+++1+++"},
+		{"https://a.com/x#top", "https://a.com/x", "https://a.com/x---#top---"},
+		{"https://a.com/x#top", "https://a.com/x#end", "https://a.com/x#---top--- +++end+++"},
+	}
+	for _, tt := range tests {
+		if got, err := Calculate(tt.prev, tt.curr, true); got != tt.want || err != nil {
+			t.Errorf("Calculate(%q, %q) = %q, %v, want %q", tt.prev, tt.curr, got, err, tt.want)
+		}
+	}
+}
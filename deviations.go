@@ -0,0 +1,64 @@
+package delta
+
+import "strings"
+
+// Span is a part of a multi-way diff: a region of the base, with what every
+// variant has in its place. Pos is the byte offset the region starts at in
+// the base, Base its text there, and Variants the text of every variant, in
+// the order they were given, which is Base itself for the variants which
+// kept it. Changed tells whether any of them didn't.
+type Span struct {
+	Pos      int
+	Base     string
+	Variants []string
+	Changed  bool
+}
+
+// Deviations compares the base to all the variants at once, such as the
+// translations or forks of the same document, and returns it in spans lined
+// up across all of them, for showing side by side in columns. Unchanged
+// spans alternate with the changed ones, which are grouped by overlapping,
+// or touching, each other, the way Conflicts does. Like patches, it's exact
+// to the byte, so the spans add up to the base and every variant.
+//
+//	delta.Deviations("the quick brown fox", []string{"the slow brown fox", "the quick brown dog"})
+//		// []Span{
+//		// 	{Pos: 0, Base: "the ", Variants: []string{"the ", "the "}},
+//		// 	{Pos: 4, Base: "quick", Variants: []string{"slow", "quick"}, Changed: true},
+//		// 	{Pos: 9, Base: " brown ", Variants: []string{" brown ", " brown "}},
+//		// 	{Pos: 16, Base: "fox", Variants: []string{"fox", "dog"}, Changed: true},
+//		// }
+func Deviations(base string, variants []string) []Span {
+	bs := segments(base)
+	sides := make([]side, len(variants))
+	for k, v := range variants {
+		sides[k] = align(bs, segments(v))
+	}
+
+	var spans []Span
+	pos := 0
+	add := func(start, end int, changed bool) {
+		text := strings.Join(bs[start:end], "")
+		if !changed && text == "" {
+			return
+		}
+		s := Span{Pos: pos, Base: text, Variants: make([]string, len(sides)), Changed: changed}
+		for k, side := range sides {
+			s.Variants[k] = text
+			if changed {
+				s.Variants[k] = side.text(start, end)
+			}
+		}
+		spans = append(spans, s)
+		pos += len(text)
+	}
+
+	last := 0
+	for _, r := range regions(sides...) {
+		add(last, r.start, false)
+		add(r.start, r.end, true)
+		last = r.end
+	}
+	add(last, len(bs), false)
+	return spans
+}
@@ -0,0 +1,69 @@
+package delta
+
+// Differ compares revisions like Calculate, with options set once, and holds
+// on to the memory it needs from one comparison to the next, so services
+// comparing revisions all day don't make it anew every time. A Differ must
+// not be used by several goroutines at once; give each one its own.
+type Differ struct {
+	opts  []Option
+	cells []int32
+	room  int
+	size  int
+}
+
+// NewDiffer returns a Differ with the given options, for comparing
+// revisions of about the given number of tokens. No memory is set aside up
+// front: it grows with the revisions compared, in steps of twice the size
+// up to what revisions of that many tokens take, and as needed past that.
+// It's never given back while the Differ is in use.
+func NewDiffer(tokens int, opts ...Option) *Differ {
+	return &Differ{opts: opts, room: (tokens + 1) * (tokens + 1)}
+}
+
+// Calculate is like the Calculate function, with the options of the Differ.
+func (d *Differ) Calculate(prev, curr string, plaintext bool) string {
+	out := calculate(prev, curr, d.options(plaintext))
+	d.size = len(out)
+	return out
+}
+
+// CalculateTokens is like the CalculateTokens function, with the options of
+// the Differ.
+func (d *Differ) CalculateTokens(prev, curr []string, plaintext bool) string {
+	out := calculateTokens(prev, curr, d.options(plaintext))
+	d.size = len(out)
+	return out
+}
+
+// options returns the options of the Differ, set up to use its memory. The
+// output is expected to be about as long as the last one.
+func (d *Differ) options(plaintext bool) *options {
+	o := newOptions(plaintext, d.opts)
+	o.cells = &d.cells
+	o.room = d.room
+	o.grow = d.size
+	return o
+}
+
+// matrix returns a matrix of zeros for sequences of the given lengths, made
+// of the memory of the Differ when there is one.
+func (o *options) matrix(rows, cols int) matrix {
+	if o.cells == nil {
+		return newMatrix(rows, cols)
+	}
+	n := (rows + 1) * (cols + 1)
+	if cap(*o.cells) < n {
+		*o.cells = make([]int32, max(n, min(2*cap(*o.cells), o.room)))
+	}
+	c := (*o.cells)[:n]
+	clear(c)
+	return matrix{cols: cols + 1, cells: c}
+}
+
+// release hands a matrix made by matrix back for reuse. The memory of a
+// Differ stays with it.
+func (o *options) release(m matrix) {
+	if o.cells == nil {
+		m.release()
+	}
+}
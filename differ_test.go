@@ -0,0 +1,18 @@
+package delta
+
+import "testing"
+
+func TestDiffer(t *testing.T) {
+	d := NewDiffer(1 << 20)
+	if cap(d.cells) != 0 {
+		t.Errorf("NewDiffer set aside %d cells, want none", cap(d.cells))
+	}
+	for _, p := range []Pair{{"hello world", "hello earth"}, {"a b c d e f", "a c e g"}, {"a", "b"}} {
+		if got, want := d.Calculate(p.Prev, p.Curr, true), Calculate(p.Prev, p.Curr, true); got != want {
+			t.Errorf("Calculate(%q, %q) = %q, want %q", p.Prev, p.Curr, got, want)
+		}
+	}
+	if cap(d.cells) > 1<<10 {
+		t.Errorf("Differ grew to %d cells for short revisions", cap(d.cells))
+	}
+}
@@ -0,0 +1,79 @@
+package delta
+
+import "strings"
+
+// WithCustomElements marks the changes of the HTML output with the custom
+// elements <delta-ins>, <delta-del> and <delta-mark> rather than <ins>, <del>
+// and <mark>, or with those of the given prefix instead of "delta", which
+// should start with a letter. Together with the script of ElementDefinition, pages get the same
+// styling of changes wherever they show them, which they can adjust through
+// CSS custom properties.
+//
+//	delta.Calculate("hello world", "hello earth", false, delta.WithCustomElements(""))
+//		// "hello <delta-del>world</delta-del> <delta-ins>earth</delta-ins>"
+func WithCustomElements(prefix string) Option {
+	return func(o *options) {
+		o.elements = "delta"
+		if prefix != "" {
+			o.elements = attributeName(prefix)
+		}
+	}
+}
+
+// tags returns the tags of insertions, deletions and corrections.
+func (o *options) tags() (ins, del, mark string) {
+	if o.elements == "" || o.plaintext {
+		return "ins", "del", "mark"
+	}
+	return o.elements + "-ins", o.elements + "-del", o.elements + "-mark"
+}
+
+// ElementDefinition returns a script defining the custom elements
+// WithCustomElements marks changes with, for the given prefix, to be served
+// as a JavaScript file or put in a script element. Insertions get a green
+// background, deletions a red one and a line through them, and corrections
+// a yellow one, which the custom properties --delta-ins-background and
+// --delta-ins-decoration, or --delta-del-… and --delta-mark-…, change;
+// changes of the class "comment" are styled down. Hovering a change
+// outlines it, with its author as the title when it has one, and the
+// elements take the roles of insertions, deletions and marks, the first two
+// as WithAccessibility gives them.
+func ElementDefinition(prefix string) string {
+	if prefix == "" {
+		prefix = "delta"
+	}
+	return strings.ReplaceAll(elementScript, "delta", attributeName(prefix))
+}
+
+// elementScript defines the elements of the prefix "delta".
+const elementScript = `(() => {
+  const define = (name, role, background, decoration) => {
+    if (customElements.get(name)) {
+      return;
+    }
+    customElements.define(name, class extends HTMLElement {
+      constructor() {
+        super();
+        this.attachShadow({mode: "open"}).innerHTML =
+          "<style>" +
+          ":host { background: var(--" + name + "-background, " + background + "); " +
+          "text-decoration: var(--" + name + "-decoration, " + decoration + "); border-radius: 2px; }" +
+          ":host(:hover) { outline: 1px solid currentColor; }" +
+          ":host(.comment) { background: none; opacity: 0.7; }" +
+          "</style><slot></slot>";
+      }
+      connectedCallback() {
+        if (!this.hasAttribute("role")) {
+          this.setAttribute("role", role);
+        }
+        if (this.dataset.author && !this.title) {
+          this.title = this.dataset.author;
+        }
+      }
+    });
+  };
+  define("delta-ins", "insertion", "#e6ffec", "none");
+  define("delta-del", "deletion", "#ffebe9", "line-through");
+  define("delta-mark", "mark", "#fff8c5", "none");
+})();
+`
@@ -0,0 +1,216 @@
+package delta
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// WithFallback compares inputs which would need a matrix of more than the
+// given number of cells, 2^24 when it's 0 or less, the cheaper way rather
+// than running out of time and memory on them: line by line first, then
+// only the words of the lines which changed, and around the words found
+// once in either revision where that's still too much, the way patience
+// diff does. What's left over is taken as replaced as a whole. The diff may
+// come out longer than it would otherwise, and Metrics tells which of
+// "lines", "anchors" or "replaced" it came to in Fallback.
+func WithFallback(cells int) Option {
+	return func(o *options) {
+		o.fallback = cells
+		if cells <= 0 {
+			o.fallback = largeMatrix
+		}
+	}
+}
+
+// fallbacks are the strategies WithFallback falls back to, cheapest last.
+var fallbacks = []string{"lines", "anchors", "replaced"}
+
+// fellBack records falling back to the strategy, unless it already fell
+// back to a cheaper one.
+func (o *options) fellBack(strategy string) {
+	if o.measured.Fallback == "" || slices.Index(fallbacks, strategy) > slices.Index(fallbacks, o.measured.Fallback) {
+		o.measured.Fallback = strategy
+	}
+}
+
+// cheaper diffs sequences too long to diff in full, as WithFallback tells.
+func (o *options) cheaper(prev, curr []string) []Edit[string] {
+	// The tokens both start and end with cost nothing to match up.
+	head := 0
+	for head < len(prev) && head < len(curr) && prev[head] == curr[head] {
+		head++
+	}
+	tail := 0
+	for tail < len(prev)-head && tail < len(curr)-head && prev[len(prev)-1-tail] == curr[len(curr)-1-tail] {
+		tail++
+	}
+	if head > 0 || tail > 0 {
+		var edits []Edit[string]
+		if head > 0 {
+			edits = append(edits, Edit[string]{Equal, prev[:head]})
+		}
+		edits = appendEdits(edits, o.align(prev[head:len(prev)-tail], curr[head:len(curr)-tail])...)
+		if tail > 0 {
+			edits = appendEdits(edits, Edit[string]{Equal, prev[len(prev)-tail:]})
+		}
+		return edits
+	}
+
+	if edits, ok := o.byLines(prev, curr); ok {
+		o.fellBack("lines")
+		return edits
+	}
+	if edits, ok := o.byAnchors(prev, curr); ok {
+		o.fellBack("anchors")
+		return edits
+	}
+
+	o.fellBack("replaced")
+	var edits []Edit[string]
+	if len(prev) > 0 {
+		edits = append(edits, Edit[string]{Delete, prev})
+	}
+	if len(curr) > 0 {
+		edits = append(edits, Edit[string]{Insert, curr})
+	}
+	return edits
+}
+
+// byLines diffs the lines of the sequences, then the tokens of the lines
+// which changed. It doesn't when there are too many lines, or no lines in
+// common.
+func (o *options) byLines(prev, curr []string) ([]Edit[string], bool) {
+	pl, cl := o.units(prev), o.units(curr)
+	if (len(pl)+1)*(len(cl)+1) > o.fallback {
+		return nil, false
+	}
+	pk, ck := make([]string, len(pl)), make([]string, len(cl))
+	for i, u := range pl {
+		pk[i] = strings.Join(u, "\x00")
+	}
+	for j, u := range cl {
+		ck[j] = strings.Join(u, "\x00")
+	}
+	lines := DiffSlices(pk, ck)
+	if !slices.ContainsFunc(lines, func(e Edit[string]) bool { return e.Op == Equal }) {
+		return nil, false
+	}
+
+	var edits []Edit[string]
+	var deleted, inserted []string
+	flush := func() {
+		switch {
+		case len(deleted) > 0 && len(inserted) > 0:
+			edits = appendEdits(edits, o.align(deleted, inserted)...)
+		case len(deleted) > 0:
+			edits = appendEdits(edits, Edit[string]{Delete, deleted})
+		case len(inserted) > 0:
+			edits = appendEdits(edits, Edit[string]{Insert, inserted})
+		}
+		deleted, inserted = nil, nil
+	}
+	i, j := 0, 0
+	for _, e := range lines {
+		for range e.Items {
+			switch e.Op {
+			case Equal:
+				flush()
+				edits = appendEdits(edits, Edit[string]{Equal, pl[i]})
+				i, j = i+1, j+1
+			case Delete:
+				deleted = append(deleted, pl[i]...)
+				i++
+			case Insert:
+				inserted = append(inserted, cl[j]...)
+				j++
+			}
+		}
+	}
+	flush()
+	return edits, true
+}
+
+// units splits the tokens into lines, with every line break a unit of its
+// own.
+func (o *options) units(tokens []string) [][]string {
+	var units [][]string
+	start := 0
+	for i, w := range tokens {
+		if w == tokenDouble || w == tokenSingle || o.tokenizer != nil && strings.Contains(w, "\n") {
+			if i > start {
+				units = append(units, tokens[start:i])
+			}
+			units = append(units, tokens[i:i+1])
+			start = i + 1
+		}
+	}
+	if start < len(tokens) {
+		units = append(units, tokens[start:])
+	}
+	return units
+}
+
+// byAnchors matches up the most tokens found only once in either sequence
+// which are in the same order in both, and diffs the tokens between them.
+// It doesn't when there are no such tokens.
+func (o *options) byAnchors(prev, curr []string) ([]Edit[string], bool) {
+	// How often every token is found in either, and where in curr.
+	type count struct{ prev, curr, at int }
+	counts := make(map[string]*count)
+	for _, w := range prev {
+		if c := counts[w]; c != nil {
+			c.prev++
+		} else {
+			counts[w] = &count{prev: 1}
+		}
+	}
+	for j, w := range curr {
+		if c := counts[w]; c != nil {
+			c.curr++
+			c.at = j
+		}
+	}
+
+	// The tokens found once in both, in the order of prev, with where
+	// they are in curr.
+	var pi, cj []int
+	for i, w := range prev {
+		if c := counts[w]; c.prev == 1 && c.curr == 1 && !o.blank(w) {
+			pi, cj = append(pi, i), append(cj, c.at)
+		}
+	}
+	if len(pi) == 0 {
+		return nil, false
+	}
+
+	// The longest run of them in the same order in curr, found by
+	// patience sorting.
+	var tails []int
+	back := make([]int, len(cj))
+	for k, j := range cj {
+		n := sort.Search(len(tails), func(t int) bool { return cj[tails[t]] >= j })
+		back[k] = -1
+		if n > 0 {
+			back[k] = tails[n-1]
+		}
+		if n == len(tails) {
+			tails = append(tails, k)
+		} else {
+			tails[n] = k
+		}
+	}
+	anchors := make([]int, len(tails))
+	for k, n := tails[len(tails)-1], len(tails)-1; n >= 0; k, n = back[k], n-1 {
+		anchors[n] = k
+	}
+
+	var edits []Edit[string]
+	i, j := 0, 0
+	for _, k := range anchors {
+		edits = appendEdits(edits, o.align(prev[i:pi[k]], curr[j:cj[k]])...)
+		edits = appendEdits(edits, Edit[string]{Equal, prev[pi[k] : pi[k]+1]})
+		i, j = pi[k]+1, cj[k]+1
+	}
+	return appendEdits(edits, o.align(prev[i:], curr[j:])...), true
+}
@@ -0,0 +1,137 @@
+package delta
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limits WithAutoGranularity picks the tokens by.
+const (
+	autoCharacters = 256     // bytes of text without spaces compared by character
+	autoLines      = 1 << 18 // bytes of text compared by line
+	autoRewritten  = 0.5     // share of new words for prose to be compared by sentence
+)
+
+// WithAutoGranularity picks the tokens to compare for every pair of
+// revisions given to Calculate, a Differ or a Cache, so callers comparing
+// all kinds of text get a readable diff without tuning it:
+//
+//   - characters for short text without spaces, such as names or codes,
+//     which words would only show as replaced as a whole
+//   - lines for text of more than 256 KiB, which would take too long to
+//     compare word by word, falling back like WithFallback when even that
+//     takes too long
+//   - sentences for prose which was mostly rewritten, judged by how many of
+//     the words of the current revision the previous one doesn't have
+//   - words for everything else, just like without it
+//
+// It replaces any tokenizer given.
+func WithAutoGranularity() Option {
+	return func(o *options) {
+		o.auto = true
+	}
+}
+
+// granularity picks the tokenizer for the revisions, when asked to.
+func (o *options) granularity(prev, curr string) {
+	if !o.auto {
+		return
+	}
+
+	size := max(len(prev), len(curr))
+	spaced := strings.ContainsFunc(prev, unicode.IsSpace) || strings.ContainsFunc(curr, unicode.IsSpace)
+	switch {
+	case size <= autoCharacters && !spaced:
+		o.tokenizer = characters
+	case size > autoLines:
+		o.tokenizer = wholeLines
+		if o.fallback <= 0 {
+			o.fallback = largeMatrix
+		}
+	case prose(curr) && rewritten(prev, curr) > autoRewritten:
+		o.tokenizer = Sentences(SentenceSegmenter(""))
+	case !o.detect:
+		o.tokenizer = nil
+	}
+}
+
+// characters splits text into characters, along with any marks on them.
+func characters(text string) []string {
+	var tokens []string
+	for len(text) > 0 {
+		_, n := utf8.DecodeRuneInString(text)
+		n += span(text[n:], func(r rune) bool { return unicode.In(r, unicode.Mn, unicode.Mc, unicode.Me) })
+		tokens = append(tokens, text[:n])
+		text = text[n:]
+	}
+	return tokens
+}
+
+// wholeLines splits text into lines.
+func wholeLines(text string) []string {
+	return appendLines(nil, text)
+}
+
+// prose tells whether the text reads like sentences, that is has a full
+// stop, question or exclamation mark followed by a space every so often.
+func prose(text string) bool {
+	ends := strings.Count(text, ". ") + strings.Count(text, "? ") + strings.Count(text, "! ")
+	return ends > 0 && len(strings.Fields(text))/ends < 40
+}
+
+// rewritten returns the share of the words of curr which prev doesn't have
+// as often, from 0 for none to 1 for all of them.
+func rewritten(prev, curr string) float64 {
+	counts := make(map[string]int)
+	for _, w := range strings.Fields(prev) {
+		counts[w]++
+	}
+	words := strings.Fields(curr)
+	if len(words) == 0 {
+		return 0
+	}
+	fresh := 0
+	for _, w := range words {
+		if counts[w] > 0 {
+			counts[w]--
+		} else {
+			fresh++
+		}
+	}
+	return float64(fresh) / float64(len(words))
+}
+
+// WithLanguageDetection picks the tokenizer for every pair of revisions
+// given to Calculate, a Differ or a Cache by the scripts they're written in,
+// so platforms taking text in any language don't have to tell which one it
+// is every time: Words for Thai, Lao, Khmer and Myanmar, which it splits into
+// characters, CJK for Chinese and Japanese, and words between spaces for
+// everything else. It replaces any tokenizer given. Along with
+// WithAutoGranularity, the tokenizer picked takes the place of words.
+func WithLanguageDetection() Option {
+	return func(o *options) {
+		o.detect = true
+	}
+}
+
+// language picks the tokenizer for the scripts of the revisions, when asked
+// to.
+func (o *options) language(prev, curr string) {
+	if !o.detect {
+		return
+	}
+
+	o.tokenizer = nil
+	for _, text := range []string{prev, curr} {
+		for _, r := range text {
+			switch {
+			case unicode.In(r, unicode.Thai, unicode.Lao, unicode.Khmer, unicode.Myanmar):
+				o.tokenizer = Words(nil)
+				return
+			case isCJK(r):
+				o.tokenizer = CJK(nil)
+			}
+		}
+	}
+}
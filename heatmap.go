@@ -0,0 +1,87 @@
+package delta
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Heat tells how much a paragraph of the current revision changed. Words is
+// how many words it has, Inserted how many of them are new and Deleted how
+// many were taken out of it. Intensity is the share of changed words among
+// all of them, from 0 for none to 1 for a paragraph which is entirely new.
+type Heat struct {
+	Words     int
+	Inserted  int
+	Deleted   int
+	Intensity float64
+}
+
+// Heatmap returns the heat of every paragraph of the current revision, in
+// order, showing where in a long document the changes are concentrated.
+// Words deleted between two paragraphs count towards the one following
+// them.
+func Heatmap(prev, curr string, opts ...Option) []Heat {
+	o := newOptions(false, opts)
+	p, c := o.tokenize(prev), o.tokenize(curr)
+
+	heat := []Heat{{}}
+	newline := false
+	for _, e := range o.diff(p, c) {
+		for _, w := range e.Items {
+			if e.Op != Delete {
+				// Tokenizers give paragraph breaks as two line
+				// breaks.
+				switch {
+				case w == tokenDouble || w == tokenSingle && newline:
+					heat = append(heat, Heat{})
+					newline = false
+					continue
+				case w == tokenSingle:
+					newline = true
+					continue
+				case w == "" || o.blank(w):
+					continue
+				}
+				newline = false
+			} else if w == "" || o.blank(w) {
+				continue
+			}
+
+			h := &heat[len(heat)-1]
+			switch e.Op {
+			case Equal:
+				h.Words++
+			case Insert:
+				h.Words++
+				h.Inserted++
+			case Delete:
+				h.Deleted++
+			}
+		}
+	}
+
+	for i, h := range heat {
+		if h.Words+h.Deleted > 0 {
+			heat[i].Intensity = float64(h.Inserted+h.Deleted) / float64(h.Words+h.Deleted)
+		}
+	}
+	return heat
+}
+
+// HeatmapHTML renders the Heatmap as a bar of one span per paragraph, with
+// an opacity following its intensity, for readers to spot the paragraphs
+// worth jumping to. The page is expected to give the spans a size and a
+// color.
+//
+//	delta.HeatmapHTML("hello world\n\nsame", "hello earth\n\nsame")
+//		// `<div class="delta-heatmap"><span class="heat" style="opacity: 0.67" title="2 of 3 words changed"></span><span class="heat" style="opacity: 0.00" title="0 of 1 words changed"></span></div>`
+func HeatmapHTML(prev, curr string, opts ...Option) string {
+	var b strings.Builder
+	b.WriteString(`<div class="delta-heatmap">`)
+	for _, h := range Heatmap(prev, curr, opts...) {
+		fmt.Fprintf(&b, `<span class="heat" style="opacity: %.2f" title="%d of %d words changed"></span>`,
+			h.Intensity, h.Inserted+h.Deleted, h.Words+h.Deleted)
+	}
+	b.WriteString("</div>")
+	return b.String()
+}
@@ -0,0 +1,52 @@
+package delta
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// DocumentFrequencies counts the documents of a corpus every word is found
+// in, for WithDocumentFrequencies. Words are counted regardless of case and
+// of the punctuation around them.
+func DocumentFrequencies(documents []string) map[string]int {
+	df := make(map[string]int)
+	for _, doc := range documents {
+		seen := make(map[string]bool)
+		for _, w := range strings.Fields(doc) {
+			w = term(w)
+			if w != "" && !seen[w] {
+				seen[w] = true
+				df[w]++
+			}
+		}
+	}
+	return df
+}
+
+// WithDocumentFrequencies weighs changes to every word by its inverse
+// document frequency in a corpus of the given number of documents, in
+// IsSignificant and the Weight of the Metrics, so changes to the terms
+// distinctive of a document count for more than changes to the words every
+// document has. A word none of the documents has counts as a whole word,
+// and one all of them have not at all.
+func WithDocumentFrequencies(df map[string]int, documents int) Option {
+	return func(o *options) {
+		o.df, o.documents = df, documents
+	}
+}
+
+// idf returns the inverse document frequency of the word, scaled to be 1
+// for words the corpus doesn't have.
+func (o *options) idf(w string) float64 {
+	if o.df == nil || o.documents <= 0 {
+		return 1
+	}
+	n := min(o.df[w], o.documents)
+	return math.Log(float64(1+o.documents)/float64(1+n)) / math.Log(float64(1+o.documents))
+}
+
+// term returns the word as weights are looked up by.
+func term(w string) string {
+	return strings.ToLower(strings.TrimFunc(w, unicode.IsPunct))
+}
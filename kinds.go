@@ -0,0 +1,134 @@
+package delta
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Kind tells what sort of change a Change is part of.
+type Kind string
+
+// Kinds of changes Changes tells apart, besides those of classifiers.
+const (
+	Insertion   Kind = "insertion"
+	Deletion    Kind = "deletion"
+	Replacement Kind = "replacement"
+	// Move is text deleted in one place and inserted in another.
+	Move Kind = "move"
+	// Whitespace, Punctuation and Case are changes to nothing else.
+	Whitespace  Kind = "whitespace"
+	Punctuation Kind = "punctuation"
+	Case        Kind = "case"
+	// Spelling is words replaced by words a letter apart, as WithTypos
+	// tells them.
+	Spelling Kind = "spelling"
+)
+
+// Classifier tells the kind of a change given the text it deletes and the
+// text it inserts, either of which is empty for insertions and deletions,
+// or returns an empty kind to leave it to the next one.
+type Classifier func(deleted, inserted string) Kind
+
+// WithClassifier has Changes ask the classifier about the kind of every
+// change first, in the order given, so callers can tell apart kinds of their
+// own, such as changes to numbers or names.
+func WithClassifier(c Classifier) Option {
+	return func(o *options) {
+		o.classifiers = append(o.classifiers, c)
+	}
+}
+
+// categorize tells the kind of every change which isn't Equal. A deletion
+// and the insertion next to it make a single change of a single kind.
+func (o *options) categorize(edits []Edit[string]) []Kind {
+	kinds := make([]Kind, len(edits))
+
+	// Text deleted in one place and inserted in another moved.
+	deleted, inserted := make(map[string]bool), make(map[string]bool)
+	for n, e := range edits {
+		if e.Op == Equal || n > 0 && edits[n-1].Op != Equal || n+1 < len(edits) && edits[n+1].Op != Equal {
+			continue
+		}
+		text := strings.TrimSpace(o.join(e.Items))
+		if e.Op == Delete {
+			deleted[text] = true
+		} else {
+			inserted[text] = true
+		}
+	}
+
+	for n := 0; n < len(edits); {
+		if edits[n].Op == Equal {
+			n++
+			continue
+		}
+		end := n + 1
+		if end < len(edits) && edits[end].Op != Equal {
+			end++
+		}
+
+		var del, ins []string
+		for _, e := range edits[n:end] {
+			if e.Op == Delete {
+				del = e.Items
+			} else {
+				ins = e.Items
+			}
+		}
+		k := o.kind(del, ins, deleted, inserted)
+		for i := n; i < end; i++ {
+			kinds[i] = k
+		}
+		n = end
+	}
+	return kinds
+}
+
+// kind tells the kind of a change deleting and inserting the given words.
+func (o *options) kind(del, ins []string, deleted, inserted map[string]bool) Kind {
+	d, i := o.join(del), o.join(ins)
+	for _, c := range o.classifiers {
+		if k := c(d, i); k != "" {
+			return k
+		}
+	}
+
+	switch {
+	case strip(d, unicode.IsSpace) == strip(i, unicode.IsSpace):
+		return Whitespace
+	case strip(d, isPunctOrSpace) == strip(i, isPunctOrSpace):
+		return Punctuation
+	case del == nil:
+		if inserted := strings.TrimSpace(i); deleted[inserted] {
+			return Move
+		}
+		return Insertion
+	case ins == nil:
+		if removed := strings.TrimSpace(d); inserted[removed] {
+			return Move
+		}
+		return Deletion
+	case strings.EqualFold(d, i):
+		return Case
+	}
+
+	if o.pairs(del, ins, o.typo) {
+		return Spelling
+	}
+	return Replacement
+}
+
+// strip removes the runes which are in from the text.
+func strip(text string, in func(rune) bool) string {
+	return strings.Map(func(r rune) rune {
+		if in(r) {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// isPunctOrSpace tells whether the rune is punctuation or whitespace.
+func isPunctOrSpace(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSpace(r)
+}
@@ -0,0 +1,279 @@
+package delta
+
+import (
+	"fmt"
+	"html"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SideBySide compares the two revisions line by line and returns an HTML
+// table showing the previous revision on the left and the current one on the
+// right. Lines which were changed rather than added or removed are paired up
+// on the same row, with the changed words inside them marked as usual.
+//
+//	delta.SideBySide("hello\nworld", "hello\nearth", delta.WithLineNumbers())
+func SideBySide(prev, curr string, opts ...Option) string {
+	o := newOptions(false, opts)
+	if o.metrics != nil {
+		defer o.report(time.Now(), len(prev), len(curr))
+	}
+	var b strings.Builder
+
+	cell := "<td>"
+	if o.bidi {
+		cell = `<td dir="auto">`
+	}
+
+	b.WriteString(`<table class="delta">` + "\n")
+	for _, r := range rows(lines(o.expand(prev)), lines(o.expand(curr)), o) {
+		left, right := "", ""
+		switch {
+		case r.op == Equal:
+			left = o.escape(o.mask(r.prev))
+			right = o.escape(o.mask(r.curr))
+		case r.paired:
+			p, c := o.tokenize(unindent(r.prev)), o.tokenize(unindent(r.curr))
+			changes := o.diff(p, c)
+			left = indent(r.prev) + rendered(only(changes, Delete), o)
+			right = indent(r.curr) + rendered(only(changes, Insert), o)
+		case r.op == Delete:
+			left = o.escape(o.mask(r.prev))
+		case r.op == Insert:
+			right = o.escape(o.mask(r.curr))
+		}
+
+		class := r.class()
+		if r.comment {
+			class += " comment"
+		}
+		b.WriteString(`<tr class="` + class + `">`)
+		if o.lineNumbers {
+			b.WriteString(`<td class="line-number">` + number(r.prevLine) + "</td>")
+		}
+		b.WriteString(cell + left + "</td>")
+		if o.lineNumbers {
+			b.WriteString(`<td class="line-number">` + number(r.currLine) + "</td>")
+		}
+		b.WriteString(cell + right + "</td></tr>\n")
+	}
+	b.WriteString("</table>")
+
+	return b.String()
+}
+
+// Unified compares the two revisions line by line and returns them as plain
+// text, one line of input per line of output. Removed lines are prefixed with
+// "-", added ones with "+", and unchanged ones with a space.
+//
+//	delta.Unified("hello\nworld", "hello\nearth")
+//		// " hello\n-world\n+earth"
+func Unified(prev, curr string, opts ...Option) string {
+	o := newOptions(true, opts)
+	if o.metrics != nil {
+		defer o.report(time.Now(), len(prev), len(curr))
+	}
+	var out []string
+
+	for _, r := range rows(lines(o.expand(prev)), lines(o.expand(curr)), o) {
+		// Paired rows are split back into a removal followed by an
+		// insertion, which is how unified diffs show changed lines.
+		if r.paired {
+			var changes []Edit[string]
+			if o.theme != nil {
+				p, c := o.tokenize(unindent(r.prev)), o.tokenize(unindent(r.curr))
+				changes = o.diff(p, c)
+			}
+			out = append(out, unified(o, Delete, r.prevLine, 0, r.prev, changes))
+			out = append(out, unified(o, Insert, 0, r.currLine, r.curr, changes))
+			continue
+		}
+
+		text := r.curr
+		if r.op == Delete {
+			text = r.prev
+		}
+		out = append(out, unified(o, r.op, r.prevLine, r.currLine, text, nil))
+	}
+
+	return strings.Join(out, "\n")
+}
+
+// unified formats a single line of the Unified output. Changes within the
+// line, if any, are only used to highlight the changed words in color.
+func unified(o *options, op Operation, prevLine, currLine int, text string, changes []Edit[string]) string {
+	prefix := " "
+	switch op {
+	case Insert:
+		prefix = "+"
+	case Delete:
+		prefix = "-"
+	}
+
+	if o.lineNumbers {
+		prefix = fmt.Sprintf("%4s %4s %s", number(prevLine), number(currLine), prefix)
+	}
+	if o.bidi {
+		prefix = markLTR + prefix
+	}
+	if o.theme == nil || op == Equal {
+		return prefix + o.mask(text)
+	}
+
+	style := o.theme.InsertLine
+	if op == Delete {
+		style = o.theme.DeleteLine
+	}
+	if changes != nil {
+		return styled(prefix, text, changes, op, o)
+	}
+	return style.start() + prefix + o.mask(text) + style.end()
+}
+
+// row is a single line of the line based output. A paired row holds a line
+// which was removed from the previous revision together with the line which
+// took its place in the current one. Line numbers start at 1, while 0 means
+// the line isn't there on that side.
+type row struct {
+	op                 Operation
+	paired             bool
+	prev, curr         string
+	prevLine, currLine int
+	comment            bool
+}
+
+// empty tells whether the line is empty or only holds whitespace.
+func empty(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+// class returns the CSS class of a row in the side by side table. Paired
+// rows which only differ in whitespace get a class of their own, so they can
+// be styled down.
+func (r row) class() string {
+	switch {
+	case r.paired && slices.Equal(strings.Fields(r.prev), strings.Fields(r.curr)):
+		return "whitespace"
+	case r.paired:
+		return "replace"
+	case r.op == Insert:
+		return "insert"
+	case r.op == Delete:
+		return "delete"
+	}
+	return "equal"
+}
+
+// rows diffs the lines of both revisions and lays the result out in rows,
+// pairing up runs of removed lines with the added lines following them.
+func rows(prev, curr []string, o *options) []row {
+	var rs []row
+	p, c := 0, 0
+
+	changes := o.diffLines(prev, curr)
+	for n := 0; n < len(changes); n++ {
+		ch := changes[n]
+
+		switch ch.Op {
+		case Equal:
+			for range ch.Items {
+				p, c = p+1, c+1
+				rs = append(rs, row{op: Equal, prev: prev[p-1], curr: curr[c-1], prevLine: p, currLine: c})
+			}
+		case Insert:
+			for _, l := range ch.Items {
+				c++
+				rs = append(rs, row{op: Insert, curr: l, currLine: c})
+			}
+		case Delete:
+			var added []string
+			if n+1 < len(changes) && changes[n+1].Op == Insert {
+				added = changes[n+1].Items
+				n++
+			}
+
+			for i := 0; i < len(ch.Items) || i < len(added); i++ {
+				r := row{op: Delete}
+				if i < len(ch.Items) {
+					p++
+					r.prev, r.prevLine = ch.Items[i], p
+				}
+				if i < len(added) {
+					c++
+					r.curr, r.currLine = added[i], c
+					r.op = Insert
+				}
+				r.paired = r.prevLine > 0 && r.currLine > 0
+				rs = append(rs, r)
+			}
+		}
+	}
+
+	if o.ignoreBlank {
+		rs = withoutBlank(rs)
+	}
+	if o.comments != nil {
+		rs = withoutComments(rs, prev, curr, o)
+	}
+	return rs
+}
+
+// withoutBlank drops the rows of blank lines which were only removed, and
+// treats those which were only added as unchanged.
+func withoutBlank(rs []row) []row {
+	kept := rs[:0]
+	for _, r := range rs {
+		switch {
+		case r.paired:
+		case r.op == Delete && empty(r.prev):
+			continue
+		case r.op == Insert && empty(r.curr):
+			r.op = Equal
+		}
+		kept = append(kept, r)
+	}
+	return kept
+}
+
+// lines splits the input into lines, normalizing line endings on the way. A
+// trailing newline does not start another, empty line.
+func lines(input string) []string {
+	input = regexpNewline.ReplaceAllString(input, "\n")
+	if input == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(input, "\n"), "\n")
+}
+
+// only keeps the unchanged words and the ones changed by the given operation.
+func only(changes []Edit[string], op Operation) []Edit[string] {
+	var kept []Edit[string]
+	for _, ch := range changes {
+		if ch.Op == Equal || ch.Op == op {
+			kept = append(kept, ch)
+		}
+	}
+	return kept
+}
+
+// indent returns the escaped leading whitespace of a line, which preprocess
+// would otherwise trim away.
+func indent(line string) string {
+	return html.EscapeString(line[:len(line)-len(unindent(line))])
+}
+
+// unindent returns the line without its indentation.
+func unindent(line string) string {
+	return strings.TrimLeft(line, " \t")
+}
+
+// number formats a line number for the gutter, leaving it blank for lines
+// which aren't there.
+func number(line int) string {
+	if line == 0 {
+		return ""
+	}
+	return strconv.Itoa(line)
+}
@@ -0,0 +1,23 @@
+package delta
+
+import "log/slog"
+
+// largeMatrix is how many cells a matrix can have before comparing takes
+// long enough, and enough memory, to be worth a warning: 64 MiB of them.
+const largeMatrix = 1 << 24
+
+// WithLogger logs warnings to l about comparisons which didn't go as well as
+// they could have, such as inputs too large to compare quickly, or options
+// which had to be ignored. Without it, nothing gets logged.
+func WithLogger(l *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// warn logs a warning, when there's a logger to log it to.
+func (o *options) warn(msg string, args ...any) {
+	if o.logger != nil {
+		o.logger.Warn(msg, args...)
+	}
+}
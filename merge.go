@@ -0,0 +1,331 @@
+package delta
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Conflict is a region of the base revision which two revisions edited
+// independently both changed, each in its own way. Pos is the byte offset
+// the region starts at in base, Base the text of the region there, and A
+// and B what either revision has in its place. Changes right next to each
+// other count as a conflict too, since they may well not read together.
+//
+// Once resolved, Resolution holds the text taken in its place. Resolve sets
+// it to either side, but any text will do, such as one edited by hand.
+type Conflict struct {
+	Pos  int
+	Base string
+	A, B string
+
+	Resolution string
+	Resolved   bool
+}
+
+// Resolve resolves the conflict with the given strategy. Markers undoes
+// the resolution, leaving the conflict to be shown between markers.
+func (c *Conflict) Resolve(choice Strategy) {
+	if choice == Markers {
+		c.Resolution, c.Resolved = "", false
+		return
+	}
+	c.Resolution, c.Resolved = resolve(*c, choice, ""), true
+}
+
+// Conflicts returns the regions of base which both a and b changed, in
+// order, without merging them, so editors can be warned before one
+// overwrites the changes of the other. Like patches, it's exact to the
+// byte. Regions both changed the same way aren't conflicts.
+//
+//	delta.Conflicts("the quick brown fox", "the slow brown fox", "the fast brown fox")
+//		// []Conflict{{Pos: 4, Base: "quick", A: "slow", B: "fast"}}
+func Conflicts(base, a, b string) []Conflict {
+	var conflicts []Conflict
+	for _, s := range threeWay(base, a, b) {
+		if s.Conflict != nil {
+			conflicts = append(conflicts, *s.Conflict)
+		}
+	}
+	return conflicts
+}
+
+// Segment is a part of a three-way merge: either Text all revisions agree
+// on once the changes are taken in, or a Conflict.
+type Segment struct {
+	Text     string
+	Conflict *Conflict
+}
+
+// Merged is a three-way merge in segments, for building merge tools on:
+// the conflicts can be shown side by side and resolved one by one, and Text
+// returns the merged text any time.
+type Merged struct {
+	Segments []Segment
+}
+
+// ThreeWay merges the changes a and b made to base, leaving the conflicts
+// to be resolved.
+//
+//	m := delta.ThreeWay("the quick brown fox", "the slow brown fox", "the fast brown fox")
+//	m.Segments[1].Conflict.Resolve(delta.Theirs)
+//	m.Text()
+//		// "the fast brown fox"
+func ThreeWay(base, a, b string) *Merged {
+	return &Merged{Segments: threeWay(base, a, b)}
+}
+
+// Unresolved returns how many conflicts are still to be resolved.
+func (m *Merged) Unresolved() int {
+	n := 0
+	for _, s := range m.Segments {
+		if s.Conflict != nil && !s.Conflict.Resolved {
+			n++
+		}
+	}
+	return n
+}
+
+// Text returns the merged text, with the conflicts which are still to be
+// resolved between conflict markers.
+func (m *Merged) Text() string {
+	var out strings.Builder
+	for _, s := range m.Segments {
+		switch {
+		case s.Conflict == nil:
+			out.WriteString(s.Text)
+		case s.Conflict.Resolved:
+			out.WriteString(s.Conflict.Resolution)
+		default:
+			out.WriteString(resolve(*s.Conflict, Markers, out.String()))
+		}
+	}
+	return out.String()
+}
+
+// side is one of the revisions of a three-way merge, aligned to the base.
+// The segments at base boundary k start at from[k] before anything inserted
+// there, and end at to[k] after it.
+type side struct {
+	segments []string
+	from, to []int
+	hunks    [][2]int
+}
+
+// align aligns the segments of a revision to those of the base.
+func align(base, segs []string) side {
+	s := side{segments: segs, from: make([]int, len(base)+1), to: make([]int, len(base)+1)}
+	i, j := 0, 0
+	start := -1
+	for _, e := range diffSegments(base, segs) {
+		if e.Op != Equal && start < 0 {
+			start = i
+		}
+		if e.Op == Equal && start >= 0 {
+			s.hunks = append(s.hunks, [2]int{start, i})
+			start = -1
+		}
+		switch e.Op {
+		case Insert:
+			j += len(e.Items)
+		case Equal:
+			for range e.Items {
+				s.to[i] = j
+				i, j = i+1, j+1
+				s.from[i] = j
+			}
+		case Delete:
+			for range e.Items {
+				s.to[i] = j
+				i++
+				s.from[i] = j
+			}
+		}
+	}
+	if start >= 0 {
+		s.hunks = append(s.hunks, [2]int{start, i})
+	}
+	s.to[i] = j
+	return s
+}
+
+// text returns what the revision has in place of the base segments from i
+// to k, including whatever it inserted at either end.
+func (s side) text(i, k int) string {
+	return strings.Join(s.segments[s.from[i]:s.to[k]], "")
+}
+
+// threeWay merges the changes a and b made to base, down to the segment.
+// Changes are grouped into regions of the base by overlapping, or touching,
+// each other; a region only one revision changed takes its change, while a
+// region both changed differently is a conflict.
+func threeWay(base, a, b string) []Segment {
+	bs := segments(base)
+	sa, sb := align(bs, segments(a)), align(bs, segments(b))
+
+	offsets := make([]int, len(bs)+1)
+	for i, s := range bs {
+		offsets[i+1] = offsets[i] + len(s)
+	}
+
+	var segs []Segment
+	add := func(text string) {
+		if text == "" {
+			return
+		}
+		if n := len(segs); n > 0 && segs[n-1].Conflict == nil {
+			segs[n-1].Text += text
+			return
+		}
+		segs = append(segs, Segment{Text: text})
+	}
+
+	last := 0
+	for _, r := range regions(sa, sb) {
+		add(strings.Join(bs[last:r.start], ""))
+		last = r.end
+		ta, tb := sa.text(r.start, r.end), sb.text(r.start, r.end)
+		switch {
+		case !r.changed[1]:
+			add(ta)
+		case !r.changed[0]:
+			add(tb)
+		case ta == tb:
+			add(ta)
+		default:
+			segs = append(segs, Segment{Conflict: &Conflict{
+				Pos:  offsets[r.start],
+				Base: strings.Join(bs[r.start:r.end], ""),
+				A:    ta,
+				B:    tb,
+			}})
+		}
+	}
+	add(strings.Join(bs[last:], ""))
+
+	return segs
+}
+
+// region is a region of the base which some of the sides changed, from
+// segment start to end, telling which ones did.
+type region struct {
+	start, end int
+	changed    []bool
+}
+
+// regions groups the changes of the sides into regions of the base, by
+// overlapping, or touching, each other.
+func regions(sides ...side) []region {
+	type hunk struct {
+		start, end int
+		side       int
+	}
+	var hunks []hunk
+	for k, s := range sides {
+		for _, h := range s.hunks {
+			hunks = append(hunks, hunk{h[0], h[1], k})
+		}
+	}
+	sort.SliceStable(hunks, func(i, j int) bool {
+		return hunks[i].start < hunks[j].start
+	})
+
+	var rs []region
+	for n := 0; n < len(hunks); {
+		r := region{start: hunks[n].start, end: hunks[n].end, changed: make([]bool, len(sides))}
+		for ; n < len(hunks) && hunks[n].start <= r.end; n++ {
+			r.end = max(r.end, hunks[n].end)
+			r.changed[hunks[n].side] = true
+		}
+		rs = append(rs, r)
+	}
+	return rs
+}
+
+// Strategy tells Merge what to do with a conflict.
+type Strategy int
+
+const (
+	// Markers keeps both sides of a conflict between conflict markers,
+	// the way git does, for someone to sort out.
+	Markers Strategy = iota
+	// Ours takes the change of a.
+	Ours
+	// Theirs takes the change of b.
+	Theirs
+	// Union keeps the change of a followed by the change of b,
+	// separated by a space unless there's whitespace between them
+	// already.
+	Union
+)
+
+// String returns the name of the strategy.
+func (s Strategy) String() string {
+	switch s {
+	case Markers:
+		return "markers"
+	case Ours:
+		return "ours"
+	case Theirs:
+		return "theirs"
+	case Union:
+		return "union"
+	}
+	return "Strategy(" + strconv.Itoa(int(s)) + ")"
+}
+
+// Merge takes the changes a and b made to base into one text, resolving
+// conflicts with the given strategy, and returns the merged text along
+// with the conflicts it ran into.
+//
+//	delta.Merge("the quick brown fox", "the slow brown fox", "the quick brown dog", delta.Ours)
+//		// "the slow brown dog", nil
+func Merge(base, a, b string, strategy Strategy) (string, []Conflict) {
+	m := ThreeWay(base, a, b)
+	var conflicts []Conflict
+	for _, s := range m.Segments {
+		if s.Conflict != nil {
+			conflicts = append(conflicts, *s.Conflict)
+			s.Conflict.Resolve(strategy)
+		}
+	}
+	return m.Text(), conflicts
+}
+
+// resolve returns what a conflict turns into with the given strategy, for
+// putting after the text merged before it.
+func resolve(c Conflict, strategy Strategy, before string) string {
+	switch strategy {
+	case Ours:
+		return c.A
+	case Theirs:
+		return c.B
+	case Union:
+		if c.A == "" || c.B == "" || strings.TrimRightFunc(c.A, unicode.IsSpace) != c.A ||
+			strings.TrimLeftFunc(c.B, unicode.IsSpace) != c.B {
+			return c.A + c.B
+		}
+		return c.A + " " + c.B
+	}
+
+	// Markers go on lines of their own.
+	var b strings.Builder
+	if before != "" && !strings.HasSuffix(before, "\n") {
+		b.WriteString("\n")
+	}
+	b.WriteString("<<<<<<< ours\n")
+	b.WriteString(line(c.A))
+	b.WriteString("=======\n")
+	b.WriteString(line(c.B))
+	b.WriteString(">>>>>>> theirs\n")
+	return b.String()
+}
+
+// line returns the text ending with a line break, unless it's empty.
+func line(text string) string {
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		return text + "\n"
+	}
+	return text
+}
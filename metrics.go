@@ -0,0 +1,69 @@
+package delta
+
+import "time"
+
+// Metrics describes the work a single call did, for services to export as
+// metrics. PrevBytes and CurrBytes are the sizes of the revisions; Items,
+// Inserted and Deleted count the tokens compared, added and removed, or the
+// lines as well when comparing line by line, which compares the lines first
+// and then the words of the changed ones. Edits counts the runs of changes.
+// Fallback names the cheaper strategy the comparison fell back to, and is
+// empty when the full comparison ran. Readability rates the diff of
+// Calculate and CalculateTokens as the function of that name does, and
+// Weight counts the words it inserted and deleted, as WithStopwords and
+// WithDocumentFrequencies weigh them.
+type Metrics struct {
+	Duration             time.Duration
+	PrevBytes, CurrBytes int
+	Items                int
+	Inserted, Deleted    int
+	Edits                int
+	Fallback             string
+	Readability          float64
+	Weight               float64
+}
+
+// WithMetrics calls record with the Metrics of every call to Calculate,
+// CalculateTokens, SideBySide or Unified once it's done. It's called on the
+// goroutine making the call, so it shouldn't block.
+func WithMetrics(record func(Metrics)) Option {
+	return func(o *options) {
+		o.metrics = record
+	}
+}
+
+// count adds a comparison to the metrics, when they're asked for.
+func (o *options) count(prev, curr []string, edits []Edit[string]) {
+	if o.metrics == nil {
+		return
+	}
+	o.measured.Items += len(prev) + len(curr)
+	for _, e := range edits {
+		switch e.Op {
+		case Insert:
+			o.measured.Inserted += len(e.Items)
+		case Delete:
+			o.measured.Deleted += len(e.Items)
+		default:
+			continue
+		}
+		o.measured.Edits++
+	}
+}
+
+// report passes the metrics of a call which started at start on to the
+// callback.
+func (o *options) report(start time.Time, prev, curr int) {
+	o.measured.Duration = time.Since(start)
+	o.measured.PrevBytes, o.measured.CurrBytes = prev, curr
+	o.metrics(o.measured)
+}
+
+// size returns the size of the tokens in bytes.
+func size(tokens []string) int {
+	n := 0
+	for _, w := range tokens {
+		n += len(w)
+	}
+	return n
+}
@@ -0,0 +1,472 @@
+package delta
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// regexpParagraph matches the blank lines between paragraphs.
+var regexpParagraph = regexp.MustCompile(`\n[^\S\n]*\n\s*`)
+
+// key returns what the token is compared by, which is the token itself
+// unless some normalization was asked for. Line breaks are kept as they are,
+// except that paragraph breaks count as line breaks when blank lines are
+// ignored.
+func (o *options) key(w string) string {
+	if w == tokenDouble && o.ignoreBlank {
+		return tokenSingle
+	}
+	if w == tokenDouble || w == tokenSingle {
+		return w
+	}
+	for _, normalize := range o.keys {
+		w = normalize(w)
+	}
+	return w
+}
+
+// diff compares the tokens of both revisions by their keys. Tokens which
+// only match by their keys count as unchanged, and are given in their
+// current form.
+func (o *options) diff(prev, curr []string) []Edit[string] {
+	edits, _ := o.covering(prev, curr)
+	return edits
+}
+
+// covering is diff, also returning how many tokens of prev every edit
+// covers. That's as many as it has items for all but the equal edits of
+// WithReflow, which hold the tokens of the current revision, whitespace
+// and all, so anything walking the tokens of prev along with the edits has
+// to go by these counts.
+func (o *options) covering(prev, curr []string) ([]Edit[string], []int) {
+	var edits []Edit[string]
+	switch {
+	case o.reflow:
+		return o.reflowed(prev, curr)
+	case !o.keyed():
+		edits = o.lcs(prev, curr)
+	default:
+		edits = o.compare(prev, curr, o.key)
+	}
+	covered := make([]int, len(edits))
+	for n, e := range edits {
+		if e.Op != Insert {
+			covered[n] = len(e.Items)
+		}
+	}
+	return edits, covered
+}
+
+// sides returns the tokens of either revision as the edits hold them. With
+// WithReflow the tokens of prev which are unchanged come out as those of
+// curr, so they line up with the edits one to one, unlike prev itself.
+func sides(edits []Edit[string]) (prev, curr []string) {
+	for _, e := range edits {
+		if e.Op != Insert {
+			prev = append(prev, e.Items...)
+		}
+		if e.Op != Delete {
+			curr = append(curr, e.Items...)
+		}
+	}
+	return prev, curr
+}
+
+// keyed tells whether diff compares the tokens by keys other than the tokens
+// themselves.
+func (o *options) keyed() bool {
+	return !o.reflow && (len(o.keys) > 0 || o.ignoreBlank)
+}
+
+// diffLines compares the lines of both revisions by the keys of their
+// tokens, like diff.
+func (o *options) diffLines(prev, curr []string) []Edit[string] {
+	if len(o.keys) == 0 {
+		return o.lcs(prev, curr)
+	}
+	return o.compare(prev, curr, func(line string) string {
+		tokens := o.tokenize(line)
+		for i, w := range tokens {
+			tokens[i] = o.key(w)
+		}
+		return strings.Join(tokens, " ")
+	})
+}
+
+// compare diffs the keys of the items, then puts the items back in place of
+// their keys.
+func (o *options) compare(prev, curr []string, key func(string) string) []Edit[string] {
+	return restore(o.lcs(keys(prev, key), keys(curr, key)), prev, curr)
+}
+
+// keys returns the keys of the items.
+func keys(items []string, key func(string) string) []string {
+	k := make([]string, len(items))
+	for i, w := range items {
+		k[i] = key(w)
+	}
+	return k
+}
+
+// restore puts the items back in place of their keys in the edits.
+func restore(edits []Edit[string], prev, curr []string) []Edit[string] {
+	i, j := 0, 0
+	for n, e := range edits {
+		switch e.Op {
+		case Equal:
+			edits[n].Items = curr[j : j+len(e.Items)]
+			i, j = i+len(e.Items), j+len(e.Items)
+		case Delete:
+			edits[n].Items = prev[i : i+len(e.Items)]
+			i += len(e.Items)
+		case Insert:
+			edits[n].Items = curr[j : j+len(e.Items)]
+			j += len(e.Items)
+		}
+	}
+	return edits
+}
+
+// WithReflow ignores how paragraphs are wrapped into lines, as well as words
+// hyphenated across a line break, so text extracted from PDFs or emails
+// compares the same no matter the width it was wrapped at. Only paragraph
+// breaks still count, and the output keeps the lines of the current
+// revision. Words are only joined again when split on spaces, and a hyphen
+// at the end of a line is always taken to be a hyphenation.
+func WithReflow() Option {
+	return func(o *options) {
+		o.reflow = true
+	}
+}
+
+// reflowed compares the tokens like covering, but with the line breaks and
+// whitespace within paragraphs left out, and hyphenated words put back
+// together.
+func (o *options) reflowed(prev, curr []string) ([]Edit[string], []int) {
+	pk, pg := o.unwrapped(prev)
+	ck, cg := o.unwrapped(curr)
+
+	edits := o.lcs(pk, ck)
+	covered := make([]int, len(edits))
+	i, j := 0, 0
+	for n, e := range edits {
+		var items []string
+		switch e.Op {
+		case Equal:
+			for _, g := range cg[j : j+len(e.Items)] {
+				items = append(items, g...)
+			}
+			for _, g := range pg[i : i+len(e.Items)] {
+				covered[n] += len(g)
+			}
+			i, j = i+len(e.Items), j+len(e.Items)
+		case Delete:
+			for _, g := range pg[i : i+len(e.Items)] {
+				items = append(items, g...)
+			}
+			covered[n] = len(items)
+			i += len(e.Items)
+		case Insert:
+			for _, g := range cg[j : j+len(e.Items)] {
+				items = append(items, g...)
+			}
+			j += len(e.Items)
+		}
+		edits[n].Items = items
+	}
+	return edits, covered
+}
+
+// unwrapped groups the tokens into words, each followed by the line breaks
+// and whitespace up to the next one, and returns the keys of the words
+// along with the groups.
+func (o *options) unwrapped(tokens []string) ([]string, [][]string) {
+	var keys []string
+	var groups [][]string
+	wrapped := false
+
+	for i := 0; i < len(tokens); i++ {
+		w := tokens[i]
+		space := w == "" || o.blank(w) && w != tokenDouble
+		switch {
+		case space && len(groups) > 0 && !(wrapped && w == tokenSingle):
+			groups[len(groups)-1] = append(groups[len(groups)-1], w)
+			wrapped = wrapped || w == tokenSingle
+			continue
+		case w == tokenSingle:
+			// Two line breaks in a row, as tokenizers give them,
+			// end the paragraph.
+			w = tokenDouble
+		}
+
+		group := []string{tokens[i]}
+		key := o.key(w)
+		if n := len(key); n > 1 && key[n-1] == '-' && i+2 < len(tokens) && tokens[i+1] == tokenSingle && !o.blank(tokens[i+2]) && tokens[i+2] != "" {
+			group = append(group, tokens[i+1], tokens[i+2])
+			key = key[:n-1] + o.key(tokens[i+2])
+			i += 2
+		}
+
+		keys = append(keys, key)
+		groups = append(groups, group)
+		wrapped = false
+	}
+	return keys, groups
+}
+
+// WithUnwrap unwraps hard wrapped paragraphs before comparing them, turning
+// the line breaks within them into spaces, so rewrapping a text file at
+// another width makes no difference. Unlike WithReflow the output is
+// unwrapped as well.
+func WithUnwrap() Option {
+	return func(o *options) {
+		o.unwrap = true
+	}
+}
+
+// unwrap joins the lines of every paragraph of the text into one.
+func unwrap(text string) string {
+	paragraphs := regexpParagraph.Split(regexpNewline.ReplaceAllString(text, "\n"), -1)
+	for i, p := range paragraphs {
+		lines := strings.Split(p, "\n")
+		for j, l := range lines {
+			lines[j] = strings.TrimSpace(l)
+		}
+		paragraphs[i] = strings.Join(lines, " ")
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// Stemmer reduces a word to its stem, so that its inflected forms, like
+// "runs" and "running", all come out the same.
+type Stemmer func(word string) string
+
+// WithStemmer compares words by their stems, as given by the stemmer, such as
+// English. Words which only differ by their inflection don't count as
+// changed then, which keeps the focus on what was actually reworded.
+// Punctuation around the words is left out of the stemming.
+func WithStemmer(s Stemmer) Option {
+	return func(o *options) {
+		o.keys = append(o.keys, func(w string) string {
+			start := strings.IndexFunc(w, isWordRune)
+			if start < 0 {
+				return w
+			}
+			end := strings.LastIndexFunc(w, isWordRune)
+			_, n := utf8.DecodeRuneInString(w[end:])
+			end += n
+			return w[:start] + s(w[start:end]) + w[end:]
+		})
+	}
+}
+
+// isWordRune tells whether the rune is part of a word rather than the
+// punctuation around it.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// typography maps the punctuation word processors like to put in to what
+// gets typed on a keyboard.
+var typography = strings.NewReplacer(
+	"‘", "'", "’", "'", "‚", "'", "‛", "'", "′", "'",
+	"“", `"`, "”", `"`, "„", `"`, "‟", `"`, "″", `"`,
+	"‐", "-", "‑", "-", "‒", "-", "–", "-", "—", "-", "―", "-", "−", "-", "--", "-",
+	"…", "...",
+	"\u00a0", " ",
+)
+
+// WithPlainPunctuation compares smart quotes, dashes and ellipses as if they
+// were their plain ASCII counterparts, so a pass through a word processor
+// doesn't make the whole document look changed. All kinds of dashes, as well
+// as a double hyphen, count as a single hyphen.
+func WithPlainPunctuation() Option {
+	return func(o *options) {
+		o.keys = append(o.keys, typography.Replace)
+	}
+}
+
+// accented and unaccented hold the Latin letters with diacritics and the
+// letters they are based on, rune for rune.
+const (
+	accented = "ÀÁÂÃÄÅÇÈÉÊËÌÍÎÏÑÒÓÔÕÖÙÚÛÜÝàáâãäåçèéêëìíî" +
+		"ïñòóôõöùúûüýÿĀāĂăĄąĆćĈĉĊċČčĎďĒēĔĕĖėĘęĚěĜ" +
+		"ĝĞğĠġĢģĤĥĨĩĪīĬĭĮįİĴĵĶķĹĺĻļĽľŃńŅņŇňŌōŎŏŐő" +
+		"ŔŕŖŗŘřŚśŜŝŞşŠšŢţŤťŨũŪūŬŭŮůŰűŲųŴŵŶŷŸŹźŻżŽ" +
+		"žƠơƯưǍǎǏǐǑǒǓǔǕǖǗǘǙǚǛǜǞǟǠǡǦǧǨǩǪǫǬǭǰǴǵǸǹǺǻ" +
+		"ȀȁȂȃȄȅȆȇȈȉȊȋȌȍȎȏȐȑȒȓȔȕȖȗȘșȚțȞȟȦȧȨȩȪȫȬȭȮȯ" +
+		"ȰȱȲȳḀḁḂḃḄḅḆḇḈḉḊḋḌḍḎḏḐḑḒḓḔḕḖḗḘḙḚḛḜḝḞḟḠḡḢḣ" +
+		"ḤḥḦḧḨḩḪḫḬḭḮḯḰḱḲḳḴḵḶḷḸḹḺḻḼḽḾḿṀṁṂṃṄṅṆṇṈṉṊṋ" +
+		"ṌṍṎṏṐṑṒṓṔṕṖṗṘṙṚṛṜṝṞṟṠṡṢṣṤṥṦṧṨṩṪṫṬṭṮṯṰṱṲṳ" +
+		"ṴṵṶṷṸṹṺṻṼṽṾṿẀẁẂẃẄẅẆẇẈẉẊẋẌẍẎẏẐẑẒẓẔẕẖẗẘẙẠạ" +
+		"ẢảẤấẦầẨẩẪẫẬậẮắẰằẲẳẴẵẶặẸẹẺẻẼẽẾếỀềỂểỄễỆệỈỉ" +
+		"ỊịỌọỎỏỐốỒồỔổỖỗỘộỚớỜờỞởỠỡỢợỤụỦủỨứỪừỬửỮữỰự" +
+		"ỲỳỴỵỶỷỸỹØøĐđŁłıĦħŦŧ"
+
+	unaccented = "AAAAAACEEEEIIIINOOOOOUUUUYaaaaaaceeeeiii" +
+		"inooooouuuuyyAaAaAaCcCcCcCcDdEeEeEeEeEeG" +
+		"gGgGgGgHhIiIiIiIiIJjKkLlLlLlNnNnNnOoOoOo" +
+		"RrRrRrSsSsSsSsTtTtUuUuUuUuUuUuWwYyYZzZzZ" +
+		"zOoUuAaIiOoUuUuUuUuUuAaAaGgKkOoOojGgNnAa" +
+		"AaAaEeEeIiIiOoOoRrRrUuUuSsTtHhAaEeOoOoOo" +
+		"OoYyAaBbBbBbCcDdDdDdDdDdEeEeEeEeEeFfGgHh" +
+		"HhHhHhHhIiIiKkKkKkLlLlLlLlMmMmMmNnNnNnNn" +
+		"OoOoOoOoPpPpRrRrRrRrSsSsSsSsSsTtTtTtTtUu" +
+		"UuUuUuUuVvVvWwWwWwWwWwXxXxYyZzZzZzhtwyAa" +
+		"AaAaAaAaAaAaAaAaAaAaAaEeEeEeEeEeEeEeEeIi" +
+		"IiOoOoOoOoOoOoOoOoOoOoOoOoUuUuUuUuUuUuUu" +
+		"YyYyYyYyOoDdLliHhTt"
+)
+
+// diacritics maps the letters with diacritics to the letters without.
+var diacritics = func() map[rune]rune {
+	m := make(map[rune]rune)
+	plain := []rune(unaccented)
+	for i, r := range []rune(accented) {
+		m[r] = plain[i]
+	}
+	return m
+}()
+
+// WithFoldedDiacritics compares letters as if they had no diacritics, so
+// "café" and "cafe" count as the same word. The output still shows the words
+// as they are written in the current revision.
+func WithFoldedDiacritics() Option {
+	return func(o *options) {
+		o.keys = append(o.keys, fold)
+	}
+}
+
+// fold takes the diacritics off the letters of the text, whether they are
+// written as letters of their own or as combining marks.
+func fold(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Mn, r) {
+			return -1
+		}
+		if plain, ok := diacritics[r]; ok {
+			return plain
+		}
+		return r
+	}, text)
+}
+
+// WithPlainEmoji compares emoji as if they had no skin tone modifiers or
+// variation selectors, so "👍🏽" and "👍", or "❤️" and "❤", count as the
+// same. The output still shows the emoji of the current revision.
+func WithPlainEmoji() Option {
+	return func(o *options) {
+		o.keys = append(o.keys, func(text string) string {
+			return strings.Map(func(r rune) rune {
+				switch {
+				case r >= 0x1f3fb && r <= 0x1f3ff, // skin tones
+					r >= 0xfe00 && r <= 0xfe0f, r >= 0xe0100 && r <= 0xe01ef: // variation selectors
+					return -1
+				}
+				return r
+			}, text)
+		})
+	}
+}
+
+// WithVolatile compares whatever matches any of the patterns, such as
+// timestamps or build numbers, as if it was the same every time, so diffs of
+// generated reports only show the changes which mean something. The output
+// still shows the values of the current revision. Patterns are matched
+// within every word, or token, on its own.
+//
+//	delta.Calculate("built 1042 ok", "built 1043 failed", true, delta.WithVolatile(regexp.MustCompile(`^\d+$`)))
+//		// "built 1043 ---ok--- +++failed+++"
+func WithVolatile(patterns ...*regexp.Regexp) Option {
+	return func(o *options) {
+		for _, re := range patterns {
+			// Each pattern gets a placeholder of its own, so a
+			// timestamp never matches a build number.
+			placeholder := "\x00" + re.String() + "\x00"
+			o.keys = append(o.keys, func(w string) string {
+				return re.ReplaceAllLiteralString(w, placeholder)
+			})
+		}
+	}
+}
+
+// WithSynonyms compares the terms of every group as the same, such as "US"
+// and "United States", or a product and its code name, so switching from one
+// to the other doesn't show as a change. The output still shows the terms
+// of the current revision. Terms of several words are taken as a single
+// token wherever they're written out in full, on one line. Terms are matched
+// as written, after whatever the options given before normalize.
+//
+//	delta.Calculate("made in the United States", "made in the US", true, delta.WithSynonyms([]string{"US", "United States"}))
+//		// "made in the US"
+func WithSynonyms(groups ...[]string) Option {
+	return func(o *options) {
+		terms := make(map[string]string)
+		for _, g := range groups {
+			for _, term := range g {
+				words := strings.Fields(term)
+				terms[strings.Join(words, " ")] = strings.Join(strings.Fields(g[0]), " ")
+				if len(words) > 1 {
+					o.phrases = append(o.phrases, words)
+				}
+			}
+		}
+		sort.SliceStable(o.phrases, func(i, j int) bool {
+			return len(o.phrases[i]) > len(o.phrases[j])
+		})
+
+		o.keys = append(o.keys, func(w string) string {
+			if t, ok := terms[w]; ok {
+				return t
+			}
+			if t, ok := terms[strings.Join(strings.Fields(w), " ")]; ok {
+				return t
+			}
+			return w
+		})
+	}
+}
+
+// phrase joins the words of the terms of several words given to
+// WithSynonyms into single tokens, the longest terms first.
+func (o *options) phrase(tokens []string) []string {
+	if o.phrases == nil {
+		return tokens
+	}
+
+	var joined []string
+	for i := 0; i < len(tokens); {
+		end := -1
+		for _, words := range o.phrases {
+			if end = o.match(tokens, i, words); end >= 0 {
+				break
+			}
+		}
+		if end < 0 {
+			joined = append(joined, tokens[i])
+			i++
+			continue
+		}
+		joined = append(joined, strings.Join(tokens[i:end], o.space()))
+		i = end
+	}
+	return joined
+}
+
+// match tells where the words end, if the tokens start with them at i, or
+// returns -1. Tokenizers give the spaces between the words as tokens too.
+func (o *options) match(tokens []string, i int, words []string) int {
+	for n, w := range words {
+		for n > 0 && o.tokenizer != nil && i < len(tokens) && strings.TrimSpace(tokens[i]) == "" && !strings.Contains(tokens[i], "\n") {
+			i++
+		}
+		if i >= len(tokens) || tokens[i] != w {
+			return -1
+		}
+		i++
+	}
+	return i
+}
@@ -0,0 +1,338 @@
+package delta
+
+import (
+	"html"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Option tweaks how Calculate renders the differences. Options which only
+// make sense for HTML are quietly ignored for plain text output.
+type Option func(*options)
+
+// options holds everything the Option functions can tune.
+type options struct {
+	plaintext bool
+
+	accessible    bool
+	hiddenClass   string
+	insertedLabel string
+	deletedLabel  string
+
+	metadata []attribute
+	tooltip  string
+	author   string
+	authors  []string
+
+	lineNumbers bool
+
+	collapse int
+	context  int
+	summary  string
+
+	maxChanges int
+	maxRatio   float64
+	replaced   string
+
+	theme *Theme
+
+	unchanged    string
+	unchangedTag string
+
+	perLine bool
+
+	insertionsFirst bool
+	grouped         bool
+
+	redact bool
+
+	sanitize Sanitizer
+
+	bidi bool
+
+	tokenizer Tokenizer
+	auto      bool
+	detect    bool
+	stages    func([]Stage) []Stage
+	filters   func([]Stage) []Stage
+
+	anchor func(string) bool
+
+	weighted bool
+
+	fallback int
+
+	keys    []func(string) string
+	phrases [][]string
+
+	tabWidth int
+
+	ignoreBlank bool
+
+	comments     *Comments
+	hideComments bool
+	commented    []bool
+
+	typos int
+	cases bool
+	kinds []string
+
+	classifiers []Classifier
+
+	statements bool
+	elements   string
+
+	stopwords map[string]bool
+	df        map[string]int
+	documents int
+
+	reflow bool
+	unwrap bool
+
+	trace io.Writer
+
+	metrics  func(Metrics)
+	measured Metrics
+
+	logger *slog.Logger
+
+	cells *[]int32
+	room  int
+	grow  int
+}
+
+// attribute is a single HTML attribute put on every change.
+type attribute struct {
+	name, value string
+}
+
+// newOptions applies the given options on top of the defaults.
+func newOptions(plaintext bool, opts []Option) *options {
+	o := &options{
+		plaintext:     plaintext,
+		hiddenClass:   "sr-only",
+		insertedLabel: "inserted: ",
+		deletedLabel:  "deleted: ",
+		summary:       "Show %d unchanged words",
+		replaced:      "Content replaced: %d words deleted, %d words inserted",
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithAccessibility makes the HTML output friendlier to screen readers. Each
+// change is marked with an ARIA role and starts with a visually hidden label,
+// such as "inserted: ", so the change gets announced and not only colored.
+// The label is hidden using the given CSS class, "sr-only" when empty, which
+// the page is expected to define.
+func WithAccessibility(class string) Option {
+	return func(o *options) {
+		o.accessible = true
+		if class != "" {
+			o.hiddenClass = class
+		}
+	}
+}
+
+// WithLabels replaces the default "inserted: " and "deleted: " labels used by
+// WithAccessibility, e.g. to translate them.
+func WithLabels(inserted, deleted string) Option {
+	return func(o *options) {
+		o.insertedLabel = inserted
+		o.deletedLabel = deleted
+	}
+}
+
+// WithMetadata attaches the given metadata, such as the revision id, author or
+// timestamp, to every change in the HTML output as data-* attributes. Keys are
+// lowercased and anything but letters, digits and dashes turns into a dash, so
+// {"Revision ID": "42"} becomes data-revision-id="42". Attributes are written
+// in the order of their names, and of their values for keys turning into the
+// same name, to keep the output stable.
+func WithMetadata(metadata map[string]string) Option {
+	return func(o *options) {
+		for key, value := range metadata {
+			o.metadata = append(o.metadata, attribute{"data-" + attributeName(key), value})
+		}
+		sort.Slice(o.metadata, func(i, j int) bool {
+			if o.metadata[i].name != o.metadata[j].name {
+				return o.metadata[i].name < o.metadata[j].name
+			}
+			return o.metadata[i].value < o.metadata[j].value
+		})
+	}
+}
+
+// attributeName turns an arbitrary key into something that is safe to use as
+// the name of an HTML attribute.
+func attributeName(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return '-'
+	}, key)
+}
+
+// WithTooltips puts the replaced text on every insertion that took the place
+// of a deletion, so readers can hover the new words to see the old ones. The
+// text goes into the given attribute, "title" when empty, which leaves room
+// for data-* attributes picked up by custom tooltip scripts.
+func WithTooltips(attr string) Option {
+	return func(o *options) {
+		o.tooltip = "title"
+		if attr != "" {
+			o.tooltip = attributeName(attr)
+		}
+	}
+}
+
+// WithLineNumbers adds gutters with the old and new line numbers to the line
+// based output of SideBySide and Unified, so reviewers can point at specific
+// lines of either revision.
+func WithLineNumbers() Option {
+	return func(o *options) {
+		o.lineNumbers = true
+	}
+}
+
+// WithCollapse folds runs of more than min unchanged words into expandable
+// <details> elements, so long documents with few changes stay short. The
+// given number of words of context is kept visible next to each change.
+func WithCollapse(min, context int) Option {
+	return func(o *options) {
+		o.collapse = min
+		o.context = context
+	}
+}
+
+// WithSummary replaces the "Show %d unchanged words" summary shown on the
+// sections folded by WithCollapse. The %d verb gets the number of words.
+func WithSummary(format string) Option {
+	return func(o *options) {
+		o.summary = format
+	}
+}
+
+// WithUnchanged wraps every run of unchanged words of the HTML output in
+// the given element, "span" when empty, with the given class, if any, so
+// pages can style or hide the text which stayed the same with CSS alone.
+//
+//	delta.Calculate("hello world", "hello earth", false, delta.WithUnchanged("", "same"))
+//		// `<span class="same">hello</span> <del>world</del> <ins>earth</ins>`
+func WithUnchanged(element, class string) Option {
+	return func(o *options) {
+		o.unchangedTag = "span"
+		if element != "" {
+			o.unchangedTag = attributeName(element)
+		}
+		o.unchanged = "<" + o.unchangedTag
+		if class != "" {
+			o.unchanged += ` class="` + html.EscapeString(class) + `"`
+		}
+		o.unchanged += ">"
+	}
+}
+
+// WithPerLineMarkers closes the markers of a change at the end of each line
+// and opens them again at the start of the next, instead of wrapping a
+// change spanning several lines as a whole. Plain text stays readable that
+// way, since every line says for itself what happened to it.
+func WithPerLineMarkers() Option {
+	return func(o *options) {
+		o.perLine = true
+	}
+}
+
+// WithRedaction masks every word of the output, leaving only the shape of the
+// changes: where they are and how long they are. Each letter turns into a
+// full block, so "hello <del>world</del>" reads "█████ <del>█████</del>".
+// Useful for showing change activity on documents that are confidential.
+func WithRedaction() Option {
+	return func(o *options) {
+		o.redact = true
+	}
+}
+
+// WithTabWidth expands the tabs in SideBySide and Unified into spaces, up to
+// the next multiple of width columns, so indentation lines up the same way
+// no matter how it was typed.
+func WithTabWidth(width int) Option {
+	return func(o *options) {
+		o.tabWidth = width
+	}
+}
+
+// expand replaces the tabs in the text by spaces, when asked to.
+func (o *options) expand(text string) string {
+	if o.tabWidth <= 0 || !strings.Contains(text, "\t") {
+		return text
+	}
+
+	var b strings.Builder
+	column := 0
+	for _, r := range text {
+		switch r {
+		case '\t':
+			n := o.tabWidth - column%o.tabWidth
+			b.WriteString(strings.Repeat(" ", n))
+			column += n
+			continue
+		case '\n':
+			column = -1
+		}
+		b.WriteRune(r)
+		column++
+	}
+	return b.String()
+}
+
+// WithoutBlankLines doesn't report blank lines which were only added or
+// removed, like diff -B, for prose where the spacing between paragraphs
+// changes all the time and doesn't matter. The output follows the current
+// revision there.
+func WithoutBlankLines() Option {
+	return func(o *options) {
+		o.ignoreBlank = true
+	}
+}
+
+// Unicode directional formatting characters used by WithBidi.
+const (
+	markIsolate = "\u2068" // first strong isolate
+	markPop     = "\u2069" // pop directional isolate
+	markLTR     = "\u200e" // left-to-right mark
+)
+
+// WithBidi keeps right-to-left and mixed-direction text, such as Hebrew or
+// Arabic, from rendering scrambled around the markers. In HTML every change
+// and every cell of SideBySide gets dir="auto", which isolates it and lets it
+// pick its own direction. Plain text wraps the text of every change in
+// Unicode directional isolates instead, and Unified starts every line with a
+// left-to-right mark so the prefixes stay on the left.
+func WithBidi() Option {
+	return func(o *options) {
+		o.bidi = true
+	}
+}
+
+// mask redacts the text when asked to, keeping the whitespace.
+func (o *options) mask(text string) string {
+	if !o.redact {
+		return text
+	}
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return r
+		}
+		return '█'
+	}, text)
+}
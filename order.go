@@ -0,0 +1,98 @@
+package delta
+
+// WithInsertionsFirst puts the insertions of a replacement before the
+// deletions they replace, instead of after them, for interfaces reading the
+// new text first. The line based output keeps its usual order.
+func WithInsertionsFirst() Option {
+	return func(o *options) {
+		o.insertionsFirst = true
+	}
+}
+
+// WithGroupedChanges gathers the words deleted and inserted across several
+// lines, with only the line breaks or whitespace between them unchanged, into
+// a single deletion and a single insertion. A rewritten passage then reads
+// as the old text followed by the new one, rather than alternating between
+// the two line by line.
+func WithGroupedChanges() Option {
+	return func(o *options) {
+		o.grouped = true
+	}
+}
+
+// arrange orders the deletions and insertions of every hunk as asked to.
+func (o *options) arrange(changes []Edit[string]) []Edit[string] {
+	if !o.insertionsFirst && !o.grouped {
+		return changes
+	}
+
+	var arranged []Edit[string]
+	for n := 0; n < len(changes); {
+		if changes[n].Op == Equal {
+			arranged = append(arranged, changes[n])
+			n++
+			continue
+		}
+
+		grouped := o.grouped && replacing(changes[n:o.hunk(changes, n, true)])
+		end := o.hunk(changes, n, grouped)
+		hunk := changes[n:end]
+		n = end
+
+		if grouped {
+			// Unchanged line breaks are part of both the old
+			// and the new text.
+			var deleted, inserted []string
+			for _, ch := range hunk {
+				if ch.Op != Insert {
+					deleted = append(deleted, ch.Items...)
+				}
+				if ch.Op != Delete {
+					inserted = append(inserted, ch.Items...)
+				}
+			}
+			hunk = []Edit[string]{{Delete, deleted}, {Insert, inserted}}
+		} else {
+			hunk = append([]Edit[string](nil), hunk...)
+		}
+
+		if o.insertionsFirst {
+			for i := 0; i+1 < len(hunk); i++ {
+				if hunk[i].Op == Delete && hunk[i+1].Op == Insert {
+					hunk[i], hunk[i+1] = hunk[i+1], hunk[i]
+					i++
+				}
+			}
+		}
+		arranged = append(arranged, hunk...)
+	}
+	return arranged
+}
+
+// hunk returns where the run of changes starting at n ends. Across
+// tells whether unchanged line breaks and whitespace followed by more
+// changes are taken as part of it.
+func (o *options) hunk(changes []Edit[string], n int, across bool) int {
+	end := n + 1
+	for end < len(changes) {
+		switch {
+		case changes[end].Op != Equal:
+			end++
+		case across && end+1 < len(changes) && changes[end+1].Op != Equal && breaksOnly(changes[end].Items, o):
+			end += 2
+		default:
+			return end
+		}
+	}
+	return end
+}
+
+// replacing tells whether the changes both delete and insert something.
+func replacing(changes []Edit[string]) bool {
+	deletes, inserts := false, false
+	for _, ch := range changes {
+		deletes = deletes || ch.Op == Delete
+		inserts = inserts || ch.Op == Insert
+	}
+	return deletes && inserts
+}
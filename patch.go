@@ -0,0 +1,454 @@
+package delta
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrMismatch is returned when a patch doesn't apply to a text, since the
+// text isn't the one the patch was made from.
+var ErrMismatch = errors.New("delta: patch does not apply")
+
+// regexpSegment splits text into words and the whitespace between them,
+// without losing a single byte.
+var regexpSegment = regexp.MustCompile(`\s+|\S+`)
+
+// patchContext is how many segments, words or whitespace, of unchanged text
+// are kept on each side of a hunk.
+const patchContext = 4
+
+// Patch is a list of changes turning one revision of a text into another.
+// Unlike the output of Calculate, a patch is exact to the byte, so applying
+// it gives back precisely the text it was made from.
+type Patch struct {
+	Hunks []Hunk `json:"hunks"`
+}
+
+// Hunk is a single change of a patch. Delete is the text taken out of the
+// previous revision at Pos, a byte offset, and Insert the text put in its
+// place. Before and After hold a bit of the unchanged text around it, for
+// checking that the patch is applied to the right text.
+type Hunk struct {
+	Pos    int    `json:"pos"`
+	Before string `json:"before,omitempty"`
+	Delete string `json:"delete,omitempty"`
+	Insert string `json:"insert,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// String returns the hunk on a line, as in `@@ 6 @@ -"world" +"earth"`,
+// leaving out the context.
+func (h Hunk) String() string {
+	s := "@@ " + strconv.Itoa(h.Pos) + " @@"
+	if h.Delete != "" {
+		s += " -" + strconv.Quote(h.Delete)
+	}
+	if h.Insert != "" {
+		s += " +" + strconv.Quote(h.Insert)
+	}
+	return s
+}
+
+// String returns the hunks of the patch, one per line.
+func (p Patch) String() string {
+	lines := make([]string, len(p.Hunks))
+	for i, h := range p.Hunks {
+		lines[i] = h.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// MarshalText encodes the patch as text, for storing it in configuration
+// files or text columns: a line for every hunk, holding its position and
+// then its before, delete, insert and after texts, each quoted as in Go.
+//
+//	6 "hello " "world," "earth," " bye"
+func (p Patch) MarshalText() ([]byte, error) {
+	var b []byte
+	for i, h := range p.Hunks {
+		if i > 0 {
+			b = append(b, '\n')
+		}
+		b = strconv.AppendInt(b, int64(h.Pos), 10)
+		for _, text := range []string{h.Before, h.Delete, h.Insert, h.After} {
+			b = append(b, ' ')
+			b = strconv.AppendQuote(b, text)
+		}
+	}
+	return b, nil
+}
+
+// UnmarshalText decodes a patch encoded by MarshalText.
+func (p *Patch) UnmarshalText(text []byte) error {
+	p.Hunks = nil
+	if len(text) == 0 {
+		return nil
+	}
+	for n, line := range strings.Split(string(text), "\n") {
+		pos, rest, _ := strings.Cut(line, " ")
+		h := Hunk{}
+		var err error
+		if h.Pos, err = strconv.Atoi(pos); err != nil {
+			return fmt.Errorf("delta: malformed patch, line %d: %w", n+1, err)
+		}
+		for _, text := range []*string{&h.Before, &h.Delete, &h.Insert, &h.After} {
+			quoted, err := strconv.QuotedPrefix(rest)
+			if err == nil {
+				*text, err = strconv.Unquote(quoted)
+			}
+			if err != nil {
+				return fmt.Errorf("delta: malformed patch, line %d: %w", n+1, err)
+			}
+			rest = strings.TrimPrefix(rest[len(quoted):], " ")
+		}
+		if rest != "" {
+			return fmt.Errorf("delta: malformed patch, line %d: unexpected %q", n+1, rest)
+		}
+		p.Hunks = append(p.Hunks, h)
+	}
+	return nil
+}
+
+// patch is a Patch without its methods, for encoding it as JSON by its
+// fields rather than as text.
+type patch Patch
+
+// MarshalJSON encodes the patch as a JSON object, rather than the text of
+// MarshalText.
+func (p Patch) MarshalJSON() ([]byte, error) {
+	return json.Marshal(patch(p))
+}
+
+// UnmarshalJSON decodes a patch encoded by MarshalJSON.
+func (p *Patch) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, (*patch)(p))
+}
+
+// hunk is a Hunk without its methods, for encoding it as JSON by its
+// fields.
+type hunk Hunk
+
+// encodedHunk is a hunk as encoded in JSON.
+type encodedHunk struct {
+	hunk
+	Base64 bool `json:"base64,omitempty"`
+}
+
+// MarshalJSON encodes the hunk as a JSON object. Patches are exact to the
+// byte, but JSON strings only hold valid UTF-8, so if any of the texts of
+// the hunk isn't, all of them are encoded in base64 and "base64" is set.
+func (h Hunk) MarshalJSON() ([]byte, error) {
+	texts := []*string{&h.Before, &h.Delete, &h.Insert, &h.After}
+	valid := true
+	for _, text := range texts {
+		valid = valid && utf8.ValidString(*text)
+	}
+	if !valid {
+		for _, text := range texts {
+			*text = base64.StdEncoding.EncodeToString([]byte(*text))
+		}
+	}
+	return json.Marshal(encodedHunk{hunk(h), !valid})
+}
+
+// UnmarshalJSON decodes a hunk encoded by MarshalJSON.
+func (h *Hunk) UnmarshalJSON(data []byte) error {
+	var e encodedHunk
+	if err := json.Unmarshal(data, &e); err != nil {
+		return err
+	}
+	if e.Base64 {
+		for _, text := range []*string{&e.Before, &e.Delete, &e.Insert, &e.After} {
+			b, err := base64.StdEncoding.DecodeString(*text)
+			if err != nil {
+				return fmt.Errorf("delta: malformed hunk: %w", err)
+			}
+			*text = string(b)
+		}
+	}
+	*h = Hunk(e.hunk)
+	return nil
+}
+
+// MakePatch returns the patch turning prev into curr.
+func MakePatch(prev, curr string) *Patch {
+	edits := diffSegments(segments(prev), segments(curr))
+
+	patch := &Patch{}
+	pos := 0
+	for n := 0; n < len(edits); n++ {
+		e := edits[n]
+		if e.Op == Equal {
+			pos += len(strings.Join(e.Items, ""))
+			continue
+		}
+
+		// Everything up to the next unchanged run goes into the
+		// same hunk.
+		h := Hunk{Pos: pos}
+		if n > 0 {
+			before := edits[n-1].Items
+			h.Before = strings.Join(before[max(0, len(before)-patchContext):], "")
+		}
+		for ; n < len(edits) && edits[n].Op != Equal; n++ {
+			text := strings.Join(edits[n].Items, "")
+			if edits[n].Op == Delete {
+				h.Delete += text
+				pos += len(text)
+			} else {
+				h.Insert += text
+			}
+		}
+		if n < len(edits) {
+			after := edits[n].Items
+			h.After = strings.Join(after[:min(len(after), patchContext)], "")
+		}
+
+		patch.Hunks = append(patch.Hunks, h)
+		n--
+	}
+
+	return patch
+}
+
+// Whitespace tells whether the hunk only changes whitespace, such as spaces
+// at the end of a line or indentation, which review tools may want to hide.
+func (h Hunk) Whitespace() bool {
+	return slices.Equal(strings.Fields(h.Delete), strings.Fields(h.Insert))
+}
+
+// Apply applies the patch to the text, which has to be the revision the
+// patch was made from. ErrMismatch is returned if it isn't.
+func (p *Patch) Apply(text string) (string, error) {
+	var b strings.Builder
+	last := 0
+
+	for i, h := range p.Hunks {
+		if !h.matches(text, h.Pos) || h.Pos < last {
+			return "", fmt.Errorf("%w: hunk %d at %d", ErrMismatch, i, h.Pos)
+		}
+
+		b.WriteString(text[last:h.Pos])
+		b.WriteString(h.Insert)
+		last = h.Pos + len(h.Delete)
+	}
+	b.WriteString(text[last:])
+
+	return b.String(), nil
+}
+
+// Status tells how a hunk went when applying a patch fuzzily.
+type Status int
+
+const (
+	// Clean hunks were applied where the patch said, context and all.
+	Clean Status = iota
+	// Fuzzy hunks were applied somewhere else, or with some of their
+	// context ignored.
+	Fuzzy
+	// Failed hunks couldn't be placed anywhere and were left out.
+	Failed
+)
+
+// String returns the name of the status.
+func (s Status) String() string {
+	switch s {
+	case Clean:
+		return "clean"
+	case Fuzzy:
+		return "fuzzy"
+	case Failed:
+		return "failed"
+	}
+	return "Status(" + strconv.Itoa(int(s)) + ")"
+}
+
+// Placement is how a single hunk went. Pos is where in the text it was
+// applied, or -1 if it failed, and Fuzz how many segments of context were
+// ignored on each side to make it fit.
+type Placement struct {
+	Status Status
+	Pos    int
+	Fuzz   int
+}
+
+// ApplyFuzzy applies the patch to a text which may have changed since the
+// patch was made, the way patch -F does. Hunks which don't fit where they
+// should are looked for elsewhere, as close as possible to where they were
+// expected, and failing that with up to fuzz segments of their context left
+// out on each side. Hunks which can't be placed at all are left out, so the
+// placements should be checked for any Failed ones.
+func (p *Patch) ApplyFuzzy(text string, fuzz int) (string, []Placement) {
+	var b strings.Builder
+	results := make([]Placement, len(p.Hunks))
+	last, offset := 0, 0
+
+	for i, h := range p.Hunks {
+		r := h.locate(text, h.Pos+offset, last, fuzz)
+		results[i] = r
+		if r.Status == Failed {
+			continue
+		}
+
+		b.WriteString(text[last:r.Pos])
+		b.WriteString(h.Insert)
+		last = r.Pos + len(h.Delete)
+		offset = r.Pos - h.Pos
+	}
+	b.WriteString(text[last:])
+
+	return b.String(), results
+}
+
+// Diagnostic is what Validate found out about a single hunk. The Placement
+// tells whether and where the hunk applies, Expected where the patch says it
+// should, and Message explains it for showing to the user.
+type Diagnostic struct {
+	Placement
+	Expected int
+	Message  string
+}
+
+// Validate checks whether the patch applies cleanly to the text, without
+// applying it. There is a diagnostic for every hunk: Clean ones apply as
+// they are, Fuzzy ones only where they were found elsewhere in the text, and
+// Failed ones not at all. Apply only succeeds if every one of them is Clean.
+func (p *Patch) Validate(text string) []Diagnostic {
+	ds := make([]Diagnostic, len(p.Hunks))
+	last := 0
+
+	for i, h := range p.Hunks {
+		d := Diagnostic{Placement: h.locate(text, h.Pos, last, 0), Expected: h.Pos}
+		switch {
+		case d.Status == Clean:
+			d.Message = fmt.Sprintf("applies at %d", h.Pos)
+		case d.Status == Fuzzy:
+			d.Message = fmt.Sprintf("found at %d instead of %d", d.Pos, h.Pos)
+		case h.Pos < last:
+			d.Message = "overlaps the previous hunk"
+		case h.Pos-len(h.Before) < 0 || h.Pos+len(h.Delete)+len(h.After) > len(text):
+			d.Message = "runs past the end of the text"
+		case text[h.Pos:h.Pos+len(h.Delete)] != h.Delete:
+			d.Message = "text to delete doesn't match"
+		default:
+			d.Message = "context doesn't match"
+		}
+		ds[i] = d
+
+		// Later hunks are checked against where this one would be
+		// applied.
+		if d.Status != Failed {
+			last = d.Pos + len(h.Delete)
+		}
+	}
+
+	return ds
+}
+
+// locate finds where the hunk fits in the text, at or past from, trying the
+// expected position first and then ignoring more and more of the context.
+func (h Hunk) locate(text string, expected, from, fuzz int) Placement {
+	if expected >= from && h.matches(text, expected) {
+		return Placement{Clean, expected, 0}
+	}
+
+	before, after := segments(h.Before), segments(h.After)
+	for f := 0; f <= fuzz; f++ {
+		// Without any text to look for, there's nothing telling where
+		// the hunk belongs.
+		if f > 0 && f >= len(before) && f >= len(after) && h.Delete == "" {
+			break
+		}
+
+		trimmed := Hunk{
+			Before: strings.Join(before[min(f, len(before)):], ""),
+			Delete: h.Delete,
+			After:  strings.Join(after[:max(len(after)-f, 0)], ""),
+		}
+		if pos, ok := trimmed.nearest(text, expected, from); ok {
+			return Placement{Fuzzy, pos, f}
+		}
+		if f >= len(before) && f >= len(after) {
+			break
+		}
+	}
+	return Placement{Failed, -1, 0}
+}
+
+// nearest finds the position closest to expected, at or past from, where
+// the hunk matches the text.
+func (h Hunk) nearest(text string, expected, from int) (int, bool) {
+	needle := h.Before + h.Delete + h.After
+	best, found := 0, false
+
+	for start := from - len(h.Before); start <= len(text)-len(needle); start++ {
+		i := strings.Index(text[max(start, 0):], needle)
+		if i < 0 {
+			break
+		}
+		start = max(start, 0) + i
+		pos := start + len(h.Before)
+		if pos >= from {
+			if !found || abs(pos-expected) < abs(best-expected) {
+				best, found = pos, true
+			} else if pos > expected {
+				break
+			}
+		}
+	}
+	return best, found
+}
+
+// abs returns the absolute value of n.
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// matches tells whether the hunk, context included, fits the text with its
+// deleted part starting at pos.
+func (h Hunk) matches(text string, pos int) bool {
+	return pos-len(h.Before) >= 0 && pos+len(h.Delete)+len(h.After) <= len(text) &&
+		text[pos-len(h.Before):pos] == h.Before &&
+		text[pos:pos+len(h.Delete)] == h.Delete &&
+		text[pos+len(h.Delete):pos+len(h.Delete)+len(h.After)] == h.After
+}
+
+// diffSegments returns the edits turning the segments of one text into
+// those of another.
+func diffSegments(p, c []string) []Edit[string] {
+	// Revisions tend to change a little here and there, so leaving the
+	// common start and end out of the matrix saves most of the work.
+	head := 0
+	for head < len(p) && head < len(c) && p[head] == c[head] {
+		head++
+	}
+	tail := 0
+	for tail < len(p)-head && tail < len(c)-head && p[len(p)-1-tail] == c[len(c)-1-tail] {
+		tail++
+	}
+
+	var edits []Edit[string]
+	if head > 0 {
+		edits = append(edits, Edit[string]{Equal, p[:head]})
+	}
+	edits = append(edits, DiffSlices(p[head:len(p)-tail], c[head:len(c)-tail])...)
+	if tail > 0 {
+		edits = append(edits, Edit[string]{Equal, p[len(p)-tail:]})
+	}
+	return edits
+}
+
+// segments splits the text into words and whitespace.
+func segments(text string) []string {
+	return regexpSegment.FindAllString(text, -1)
+}
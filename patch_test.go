@@ -0,0 +1,37 @@
+package delta
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestPatchJSON(t *testing.T) {
+	for _, texts := range [][2]string{
+		{"hello world", "hello earth"},
+		{"hello \xff world", "hello \xfe earth"},
+	} {
+		p := MakePatch(texts[0], texts[1])
+		data, err := json.Marshal(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got Patch
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(&got, p) {
+			t.Errorf("patch %s decoded as %#v, want %#v", data, got, *p)
+		}
+		if text, err := got.Apply(texts[0]); text != texts[1] || err != nil {
+			t.Errorf("Apply = %q, %v, want %q", text, err, texts[1])
+		}
+	}
+}
+
+func TestPatchString(t *testing.T) {
+	p := *MakePatch("hello world", "hello earth")
+	if got, want := p.String(), `@@ 6 @@ -"world" +"earth"`; got != want {
+		t.Errorf("String = %q, want %q", got, want)
+	}
+}
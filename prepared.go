@@ -0,0 +1,55 @@
+package delta
+
+import "time"
+
+// Prepared is a base document split into tokens and normalized once, for
+// comparing many revisions against the same base, such as submissions
+// against the template they were made from. A Prepared is safe for use by
+// several goroutines at once.
+type Prepared struct {
+	opts   []Option
+	base   string
+	tokens []string
+	keys   []string
+}
+
+// Prepare splits and normalizes the base document with the given options,
+// which then apply to every comparison against it. WithAutoGranularity and
+// WithLanguageDetection pick how to split the text by both revisions, so
+// with either of them the base is split again for every comparison.
+func Prepare(base string, opts ...Option) *Prepared {
+	o := newOptions(false, opts)
+	p := &Prepared{opts: opts, base: base}
+	if o.auto || o.detect {
+		return p
+	}
+	p.tokens = o.tokenize(base)
+	if o.keyed() {
+		p.keys = keys(p.tokens, o.key)
+	}
+	return p
+}
+
+// Calculate is like the Calculate function, comparing the base document as
+// the previous revision to curr.
+func (p *Prepared) Calculate(curr string, plaintext bool) string {
+	o := newOptions(plaintext, p.opts)
+	if o.metrics != nil {
+		defer o.report(time.Now(), len(p.base), len(curr))
+	}
+	tokens := p.tokens
+	if o.auto || o.detect {
+		o.language(p.base, curr)
+		o.granularity(p.base, curr)
+		tokens = o.tokenize(p.base)
+	}
+	c := o.tokenize(curr)
+
+	var changes []Edit[string]
+	if p.keys != nil {
+		changes = restore(o.lcs(p.keys, keys(c, o.key)), tokens, c)
+	} else {
+		changes = o.diff(tokens, c)
+	}
+	return o.output(changes, tokens, c)
+}
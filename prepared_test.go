@@ -0,0 +1,21 @@
+package delta
+
+import "testing"
+
+func TestPrepared(t *testing.T) {
+	tests := []struct {
+		base, curr string
+		opts       []Option
+	}{
+		{"hello world", "hello earth", nil},
+		{"cafe world", "café earth", []Option{WithFoldedDiacritics()}},
+		{"AB-1234-XY", "AB-1243-XY", []Option{WithAutoGranularity()}},
+		{"我喜欢猫。", "我喜欢狗。", []Option{WithLanguageDetection()}},
+	}
+	for _, tt := range tests {
+		got := Prepare(tt.base, tt.opts...).Calculate(tt.curr, true)
+		if want := Calculate(tt.base, tt.curr, true, tt.opts...); got != want {
+			t.Errorf("Prepare(%q).Calculate(%q) = %q, want %q", tt.base, tt.curr, got, want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package delta
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Readability rates how easy the edits of a diff of text are to read, from
+// 0 to 1, for comparing the diffs options make of the same revisions. Diffs
+// read best when the changes come in few runs rather than scattered word by
+// word, aren't broken up by one or two unchanged words, and start and end at
+// the edges of lines, sentences or clauses. It weighs those three, with the
+// first counting most. Diffs without changes rate 1.
+//
+//	delta.Readability(delta.DiffSlices(strings.Fields("a b c"), strings.Fields("x b y")))
+//		// 0.45
+func Readability(edits []Edit[string]) float64 {
+	runs, changed, islands, tiny, edges := 0, 0, 0, 0, 0
+	for n, e := range edits {
+		if e.Op == Equal {
+			if n > 0 && n < len(edits)-1 {
+				islands++
+				if words(e.Items) <= 2 {
+					tiny++
+				}
+			}
+			continue
+		}
+		changed += words(e.Items)
+		if n > 0 && edits[n-1].Op != Equal {
+			continue
+		}
+
+		// Where the run of changes starts and ends.
+		runs++
+		end := n
+		for end+1 < len(edits) && edits[end+1].Op != Equal {
+			end++
+		}
+		if n == 0 || boundary(edits[n-1].Items, len(edits[n-1].Items)-1, -1) {
+			edges++
+		}
+		if end == len(edits)-1 || boundary(edits[end+1].Items, 0, 1) || boundary(edits[end].Items, len(edits[end].Items)-1, -1) {
+			edges++
+		}
+	}
+	if runs == 0 {
+		return 1
+	}
+
+	fragmentation := min(1, float64(runs-1)/float64(max(1, changed)))
+	broken := 0.0
+	if islands > 0 {
+		broken = float64(tiny) / float64(islands)
+	}
+	return 0.4*(1-fragmentation) + 0.3*(1-broken) + 0.3*float64(edges)/float64(2*runs)
+}
+
+// words counts the tokens which aren't only whitespace.
+func words(tokens []string) int {
+	n := 0
+	for _, w := range tokens {
+		if strings.TrimSpace(w) != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// boundary tells whether the first token which isn't only spaces, going
+// from the given index in the given direction, is a line break or ends a
+// sentence or clause.
+func boundary(tokens []string, from, dir int) bool {
+	for i := from; i >= 0 && i < len(tokens); i += dir {
+		w := tokens[i]
+		if strings.Contains(w, "\n") {
+			return true
+		}
+		if strings.TrimSpace(w) == "" {
+			continue
+		}
+		if dir > 0 {
+			return false
+		}
+		r, _ := utf8.DecodeLastRuneInString(w)
+		return unicode.IsPunct(r) && strings.ContainsRune(".,;:!?", r)
+	}
+	return false
+}
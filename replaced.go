@@ -0,0 +1,76 @@
+package delta
+
+import (
+	"fmt"
+	"html"
+)
+
+// WithMaxChanges shows a summary of how much changed instead of the diff
+// when it has more than n runs of changes, since a document which was
+// mostly rewritten reads worse as a diff than as the new revision. The
+// summary can be changed with WithReplacedSummary.
+func WithMaxChanges(n int) Option {
+	return func(o *options) {
+		o.maxChanges = n
+	}
+}
+
+// WithMaxChangedRatio shows a summary of how much changed instead of the
+// diff, like WithMaxChanges, when more than the given share of the words
+// changed, from 0 to 1. The share is counted like the Intensity of Heat,
+// over the words of the current revision and the deleted ones.
+func WithMaxChangedRatio(ratio float64) Option {
+	return func(o *options) {
+		o.maxRatio = ratio
+	}
+}
+
+// WithReplacedSummary replaces the "Content replaced: %d words deleted, %d
+// words inserted" summary shown by WithMaxChanges and WithMaxChangedRatio.
+// The %d verbs get the number of words deleted and inserted, in that order.
+func WithReplacedSummary(format string) Option {
+	return func(o *options) {
+		o.replaced = format
+	}
+}
+
+// replacement returns the summary to show instead of the changes when
+// there are too many of them, or an empty string.
+func (o *options) replacement(changes []Edit[string]) string {
+	if o.maxChanges <= 0 && o.maxRatio <= 0 {
+		return ""
+	}
+
+	// A deletion and the insertion replacing it make a single run.
+	runs, same, inserted, deleted := 0, 0, 0, 0
+	for n, e := range changes {
+		if e.Op != Equal && !breaksOnly(e.Items, o) && (n == 0 || changes[n-1].Op == Equal) {
+			runs++
+		}
+		for _, w := range e.Items {
+			if w == "" || o.blank(w) {
+				continue
+			}
+			switch e.Op {
+			case Equal:
+				same++
+			case Insert:
+				inserted++
+			case Delete:
+				deleted++
+			}
+		}
+	}
+
+	total := same + inserted + deleted
+	if (o.maxChanges <= 0 || runs <= o.maxChanges) &&
+		(o.maxRatio <= 0 || total == 0 || float64(inserted+deleted)/float64(total) <= o.maxRatio) {
+		return ""
+	}
+
+	summary := fmt.Sprintf(o.replaced, deleted, inserted)
+	if o.plaintext {
+		return summary
+	}
+	return `<p class="delta-replaced">` + html.EscapeString(summary) + "</p>"
+}
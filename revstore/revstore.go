@@ -0,0 +1,176 @@
+// Revstore stores the history of a document as a delta chain: the first
+// revision in full, followed by the patches turning each revision into the
+// next one. Documents edited a little at a time take a fraction of the space
+// their revisions would in full, while any of them can still be had back.
+// Chains are kept in memory, and saved to and loaded from a Store.
+//
+// Examples:
+//
+//	c := revstore.New("hello world")
+//	c.Append("hello earth")
+//	c.Revision(0)
+//		// "hello world"
+package revstore
+
+import (
+	"errors"
+
+	"github.com/nkrs/delta"
+)
+
+// ErrNotFound is returned when asking for a revision the chain doesn't have.
+var ErrNotFound = errors.New("revstore: revision not found")
+
+// Chain is the history of a single document. Revisions are numbered from 0,
+// the base revision, up.
+type Chain struct {
+	base    string
+	patches []*delta.Patch
+	head    string
+	full    int
+
+	every       int
+	checkpoints map[int]string
+}
+
+// New returns a chain starting with the given base revision.
+func New(base string) *Chain {
+	return &Chain{base: base, head: base, full: len(base)}
+}
+
+// Append adds a revision to the end of the chain and returns its number.
+func (c *Chain) Append(text string) int {
+	c.patches = append(c.patches, delta.MakePatch(c.head, text))
+	c.head = text
+	c.full += len(text)
+	if c.every > 0 && len(c.patches)%c.every == 0 {
+		c.checkpoints[len(c.patches)] = text
+	}
+	return len(c.patches)
+}
+
+// KeepCheckpoints keeps every n-th revision in full as well, so getting a
+// revision back only takes applying the patches after the checkpoint before
+// it, rather than all of them. Documents with thousands of revisions stay
+// quick to read that way, for a bit more space. A value of 0 or less stops
+// keeping checkpoints. Checkpoints are only kept in memory, and aren't
+// saved to a Store.
+func (c *Chain) KeepCheckpoints(n int) {
+	c.every, c.checkpoints = n, nil
+	if n <= 0 {
+		return
+	}
+
+	c.checkpoints = make(map[int]string)
+	text := c.base
+	for i, p := range c.patches {
+		// Patches made by the chain always apply to the revision
+		// before them.
+		text, _ = p.Apply(text)
+		if (i+1)%n == 0 {
+			c.checkpoints[i+1] = text
+		}
+	}
+}
+
+// Len returns the number of revisions in the chain.
+func (c *Chain) Len() int {
+	return len(c.patches) + 1
+}
+
+// Head returns the latest revision.
+func (c *Chain) Head() string {
+	return c.head
+}
+
+// Revision returns the revision with the given number, by applying the
+// patches up to it to the base revision, or to the checkpoint before it.
+func (c *Chain) Revision(n int) (string, error) {
+	if n < 0 || n >= c.Len() {
+		return "", ErrNotFound
+	}
+	if n == len(c.patches) {
+		return c.head, nil
+	}
+
+	text, from := c.base, 0
+	if c.every > 0 && n >= c.every {
+		from = n - n%c.every
+		text = c.checkpoints[from]
+	}
+	for _, p := range c.patches[from:n] {
+		var err error
+		if text, err = p.Apply(text); err != nil {
+			return "", err
+		}
+	}
+	return text, nil
+}
+
+// Squash replaces the patches between revisions from and to with a single
+// one, reclaiming the space of old history. The revisions in between are
+// gone afterwards, and the ones after to move down to take their place, so
+// to becomes from+1. Since the revisions are numbered anew, a chain saved
+// to a Store before has to be saved to a new one.
+func (c *Chain) Squash(from, to int) error {
+	if from < 0 || to >= c.Len() || from >= to {
+		return ErrNotFound
+	}
+
+	prev, err := c.Revision(from)
+	if err != nil {
+		return err
+	}
+	text := prev
+	for _, p := range c.patches[from:to] {
+		if text, err = p.Apply(text); err != nil {
+			return err
+		}
+		c.full -= len(text)
+	}
+	c.full += len(text)
+
+	patches := append(c.patches[:from:from], delta.MakePatch(prev, text))
+	c.patches = append(patches, c.patches[to:]...)
+	c.KeepCheckpoints(c.every)
+	return nil
+}
+
+// Stats tells how much space the chain saves. Full is the size of all the
+// revisions stored in full, Stored the size of the base revision, the
+// patches and the checkpoints. Both are in bytes.
+type Stats struct {
+	Revisions int
+	Full      int
+	Stored    int
+}
+
+// Saved returns the fraction of space saved, between 0 and 1.
+func (s Stats) Saved() float64 {
+	if s.Full == 0 {
+		return 0
+	}
+	return 1 - float64(s.Stored)/float64(s.Full)
+}
+
+// Stats returns the storage statistics of the chain.
+func (c *Chain) Stats() Stats {
+	s := Stats{Revisions: c.Len(), Full: c.full, Stored: len(c.base)}
+	for _, p := range c.patches {
+		s.Stored += size(p)
+	}
+	for _, text := range c.checkpoints {
+		s.Stored += len(text)
+	}
+	return s
+}
+
+// size estimates the stored size of a patch: the text of its hunks, and a
+// few bytes for the position and lengths of each.
+func size(p *delta.Patch) int {
+	n := 0
+	for _, h := range p.Hunks {
+		n += 8 + len(h.Before) + len(h.Delete) + len(h.Insert) + len(h.After)
+	}
+	return n
+}
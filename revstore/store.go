@@ -0,0 +1,223 @@
+package revstore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/nkrs/delta"
+)
+
+// Record is a revision as it's stored: the text of the base revision, or
+// the patch turning the revision before into it for the others.
+type Record struct {
+	Text  string
+	Patch *delta.Patch
+}
+
+// Store keeps the records of a chain, so chains can be kept anywhere, such
+// as in files, a database or a bucket, by implementing it. Revisions are
+// only ever added, in order.
+type Store interface {
+	// Put stores the record of the revision with the given number.
+	Put(n int, r Record) error
+	// GetRevision returns the record of the revision with the given
+	// number, or ErrNotFound.
+	GetRevision(n int) (Record, error)
+	// ListRevisions returns the numbers of the revisions stored, in
+	// order.
+	ListRevisions() ([]int, error)
+}
+
+// Save adds the revisions the store doesn't have yet to it, which are all
+// of them the first time around.
+func (c *Chain) Save(s Store) error {
+	stored, err := s.ListRevisions()
+	if err != nil {
+		return err
+	}
+	if len(stored) > c.Len() {
+		return fmt.Errorf("revstore: store holds %d revisions, the chain only %d", len(stored), c.Len())
+	}
+
+	for n := len(stored); n < c.Len(); n++ {
+		r := Record{Text: c.base}
+		if n > 0 {
+			r = Record{Patch: c.patches[n-1]}
+		}
+		if err := s.Put(n, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Load reads the chain kept in the store.
+func Load(s Store) (*Chain, error) {
+	stored, err := s.ListRevisions()
+	if err != nil {
+		return nil, err
+	}
+	if len(stored) == 0 {
+		return nil, ErrNotFound
+	}
+
+	var c *Chain
+	for i, n := range stored {
+		if n != i {
+			return nil, fmt.Errorf("revstore: revision %d is missing from the store", i)
+		}
+		r, err := s.GetRevision(n)
+		if err != nil {
+			return nil, err
+		}
+
+		if n == 0 {
+			c = New(r.Text)
+			continue
+		}
+		if r.Patch == nil {
+			return nil, fmt.Errorf("revstore: revision %d has no patch", n)
+		}
+		text, err := r.Patch.Apply(c.head)
+		if err != nil {
+			return nil, err
+		}
+		c.patches = append(c.patches, r.Patch)
+		c.head = text
+		c.full += len(text)
+	}
+	return c, nil
+}
+
+// Memory is a Store keeping the records in memory, which is mostly useful
+// for tests. It's safe for use by several goroutines at once.
+type Memory struct {
+	mu      sync.Mutex
+	records []Record
+}
+
+// NewMemory returns an empty Memory store.
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// Put stores the record, which has to be the next one.
+func (m *Memory) Put(n int, r Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if n != len(m.records) {
+		return fmt.Errorf("revstore: revision %d put out of order, expected %d", n, len(m.records))
+	}
+	m.records = append(m.records, r)
+	return nil
+}
+
+// GetRevision returns the record of the revision.
+func (m *Memory) GetRevision(n int) (Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if n < 0 || n >= len(m.records) {
+		return Record{}, ErrNotFound
+	}
+	return m.records[n], nil
+}
+
+// ListRevisions returns the numbers of the revisions stored.
+func (m *Memory) ListRevisions() ([]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ns := make([]int, len(m.records))
+	for i := range ns {
+		ns[i] = i
+	}
+	return ns, nil
+}
+
+// Dir is a Store keeping every record in a file of its own in a directory:
+// the base revision as text in 0.txt, and the patches in the binary
+// encoding of delta.Patch in 1.patch, 2.patch and so on.
+type Dir struct {
+	path string
+}
+
+// NewDir returns a Dir store keeping the records in the directory at path,
+// which is created if it isn't there.
+func NewDir(path string) (*Dir, error) {
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		return nil, err
+	}
+	return &Dir{path: path}, nil
+}
+
+// file returns the path of the file holding the record of a revision.
+func (d *Dir) file(n int) string {
+	if n == 0 {
+		return filepath.Join(d.path, "0.txt")
+	}
+	return filepath.Join(d.path, strconv.Itoa(n)+".patch")
+}
+
+// Put writes the record to its file.
+func (d *Dir) Put(n int, r Record) error {
+	data := []byte(r.Text)
+	if n > 0 {
+		if r.Patch == nil {
+			return fmt.Errorf("revstore: revision %d has no patch", n)
+		}
+		var err error
+		if data, err = r.Patch.MarshalBinary(); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(d.file(n), data, 0o644)
+}
+
+// GetRevision reads the record of the revision from its file.
+func (d *Dir) GetRevision(n int) (Record, error) {
+	if n < 0 {
+		return Record{}, ErrNotFound
+	}
+	data, err := os.ReadFile(d.file(n))
+	if errors.Is(err, os.ErrNotExist) {
+		return Record{}, ErrNotFound
+	} else if err != nil {
+		return Record{}, err
+	}
+
+	if n == 0 {
+		return Record{Text: string(data)}, nil
+	}
+	p := &delta.Patch{}
+	if err := p.UnmarshalBinary(data); err != nil {
+		return Record{}, err
+	}
+	return Record{Patch: p}, nil
+}
+
+// ListRevisions returns the numbers of the revisions in the directory.
+func (d *Dir) ListRevisions() ([]int, error) {
+	entries, err := os.ReadDir(d.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var ns []int
+	for _, e := range entries {
+		name := e.Name()
+		if name == "0.txt" {
+			ns = append(ns, 0)
+			continue
+		}
+		if n, err := strconv.Atoi(strings.TrimSuffix(name, ".patch")); err == nil && n > 0 && strings.HasSuffix(name, ".patch") {
+			ns = append(ns, n)
+		}
+	}
+	sort.Ints(ns)
+	return ns, nil
+}
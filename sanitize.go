@@ -0,0 +1,65 @@
+package delta
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// regexpTag matches an opening, closing or self-closing tag without any
+// attributes.
+var regexpTag = regexp.MustCompile(`<(/?)([a-zA-Z][a-zA-Z0-9]*)\s*(/?)>`)
+
+// Sanitizer makes a piece of untrusted HTML safe to show, by escaping or
+// leaving out anything which could run scripts or break out of the page.
+type Sanitizer func(text string) string
+
+// WithHTML takes the revisions to be HTML, such as comments users wrote with
+// some formatting, and shows their markup as markup rather than escaping it.
+// Every word, and every line of SideBySide, is passed through sanitize
+// first, as revisions can't be trusted to be safe. Text is still split into
+// words at spaces, so tags with attributes end up split across words, which
+// the sanitizer should escape; AllowTags does. Plain text ignores it.
+func WithHTML(sanitize Sanitizer) Option {
+	return func(o *options) {
+		o.sanitize = sanitize
+	}
+}
+
+// AllowTags returns a Sanitizer keeping the given tags, such as "b" or "em",
+// as long as they have no attributes, and escaping everything else. Entities
+// are kept as they are. Tags aren't balanced, so a change opening one
+// without closing it may style the text after it too.
+//
+//	delta.Calculate("a <b>bold</b> move", "a <b>brave</b> move", false, delta.WithHTML(delta.AllowTags("b")))
+//		// "a <del><b>bold</b></del> <ins><b>brave</b></ins> move"
+func AllowTags(tags ...string) Sanitizer {
+	allowed := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		allowed[strings.ToLower(t)] = true
+	}
+	return func(text string) string {
+		var b strings.Builder
+		last := 0
+		for _, m := range regexpTag.FindAllStringSubmatchIndex(text, -1) {
+			name := strings.ToLower(text[m[4]:m[5]])
+			if !allowed[name] {
+				continue
+			}
+			b.WriteString(html.EscapeString(html.UnescapeString(text[last:m[0]])))
+			b.WriteString("<" + text[m[2]:m[3]] + name + text[m[6]:m[7]] + ">")
+			last = m[1]
+		}
+		b.WriteString(html.EscapeString(html.UnescapeString(text[last:])))
+		return b.String()
+	}
+}
+
+// escape makes the text safe to put in the HTML output, by escaping it, or
+// sanitizing it when it's HTML itself.
+func (o *options) escape(text string) string {
+	if o.sanitize != nil && !o.plaintext {
+		return o.sanitize(text)
+	}
+	return html.EscapeString(text)
+}
@@ -0,0 +1,142 @@
+package delta
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// abbreviations holds the abbreviations ending in a full stop which don't
+// end a sentence, by language. Single letters, as in initials, never do
+// either.
+var abbreviations = map[string][]string{
+	"en": {"mr", "mrs", "ms", "dr", "prof", "sr", "jr", "st", "vs", "etc", "e.g", "i.e", "cf", "approx", "no", "fig", "inc", "ltd", "co", "jan", "feb", "mar", "apr", "jun", "jul", "aug", "sep", "sept", "oct", "nov", "dec"},
+	"de": {"dr", "prof", "hr", "fr", "nr", "str", "bzw", "usw", "ca", "z.b", "d.h", "u.a", "vgl", "evtl", "ggf", "inkl", "bspw", "jan", "feb", "mär", "apr", "jun", "jul", "aug", "sep", "sept", "okt", "nov", "dez"},
+	"es": {"sr", "sra", "srta", "dr", "dra", "ud", "uds", "etc", "p.ej", "pág", "núm", "aprox", "av", "ene", "feb", "mar", "abr", "jun", "jul", "ago", "sept", "oct", "nov", "dic"},
+	"fr": {"m", "mme", "mlle", "dr", "pr", "st", "ste", "etc", "p.ex", "cf", "env", "janv", "févr", "avr", "juil", "sept", "oct", "nov", "déc"},
+}
+
+// Sentences returns a tokenizer splitting text into sentences, which makes
+// for a coarser diff of prose: a sentence is either kept or rewritten. The
+// sentences of every line are found by the given segmenter, such as the one
+// of SentenceSegmenter, and the whitespace between them is split off.
+//
+//	delta.Calculate("Hi. How are you?", "Hi. How is it going?", true,
+//		delta.WithTokenizer(delta.Sentences(delta.SentenceSegmenter("en"))))
+//		// "Hi. ---How are you?---+++How is it going?+++"
+func Sentences(segment Segmenter) Tokenizer {
+	return func(text string) []string {
+		var tokens []string
+		for i, line := range strings.Split(text, "\n") {
+			if i > 0 {
+				tokens = append(tokens, "\n")
+			}
+			for _, sentence := range segment(line) {
+				body := strings.TrimLeftFunc(sentence, unicode.IsSpace)
+				lead := sentence[:len(sentence)-len(body)]
+				trail := body[len(strings.TrimRightFunc(body, unicode.IsSpace)):]
+				body = body[:len(body)-len(trail)]
+
+				for _, t := range []string{lead, body, trail} {
+					if t != "" {
+						tokens = append(tokens, t)
+					}
+				}
+			}
+		}
+		return tokens
+	}
+}
+
+// SentenceSegmenter returns a segmenter splitting text into sentences, by
+// the conventions of the given language, such as "en" or "es-MX". Sentences
+// end at a full stop, question or exclamation mark followed by a space, or
+// at the full width marks of Chinese and Japanese, which need none. Full
+// stops after the abbreviations of the language, after initials, or before a
+// word in lowercase don't end a sentence. Languages it doesn't know only get
+// the last two rules.
+func SentenceSegmenter(locale string) Segmenter {
+	lang, _, _ := strings.Cut(strings.ToLower(locale), "-")
+	lang, _, _ = strings.Cut(lang, "_")
+	known := make(map[string]bool)
+	for _, a := range abbreviations[lang] {
+		known[a] = true
+	}
+
+	return func(text string) []string {
+		var sentences []string
+		start := 0
+
+		for i := 0; i < len(text); {
+			r, n := utf8.DecodeRuneInString(text[i:])
+			if !terminator(r) {
+				i += n
+				continue
+			}
+
+			// A sentence ends after all of its marks and whatever
+			// quotes or brackets close it.
+			end := i + span(text[i:], terminator)
+			end += span(text[end:], closing)
+			next := end + span(text[end:], unicode.IsSpace)
+
+			switch {
+			case fullWidth(r):
+			case end == len(text):
+			case next == end:
+				i = end
+				continue
+			case r == '.' && end == i+1 && abbreviated(text[start:i], known):
+				i = end
+				continue
+			case r == '.' && lowercase(text[next:]):
+				i = end
+				continue
+			}
+
+			sentences = append(sentences, text[start:next])
+			start, i = next, next
+		}
+
+		if start < len(text) {
+			sentences = append(sentences, text[start:])
+		}
+		return sentences
+	}
+}
+
+// terminator tells whether the rune may end a sentence.
+func terminator(r rune) bool {
+	switch r {
+	case '.', '!', '?', '…', '‼', '⁇', '⁈', '⁉', '。', '！', '？', '｡':
+		return true
+	}
+	return false
+}
+
+// fullWidth tells whether the rune ends a sentence all by itself.
+func fullWidth(r rune) bool {
+	return r == '。' || r == '！' || r == '？' || r == '｡'
+}
+
+// closing tells whether the rune closes a quote or brackets.
+func closing(r rune) bool {
+	return unicode.In(r, unicode.Pe, unicode.Pf) || r == '"' || r == '\''
+}
+
+// abbreviated tells whether the text before a full stop ends in an
+// abbreviation or an initial.
+func abbreviated(text string, known map[string]bool) bool {
+	word := text[strings.LastIndexFunc(text, unicode.IsSpace)+1:]
+	word = strings.TrimLeftFunc(word, func(r rune) bool { return !unicode.IsLetter(r) })
+	if utf8.RuneCountInString(word) == 1 && unicode.IsUpper([]rune(word)[0]) {
+		return true
+	}
+	return known[strings.ToLower(word)]
+}
+
+// lowercase tells whether the text goes on with a word in lowercase.
+func lowercase(text string) bool {
+	r, _ := utf8.DecodeRuneInString(text)
+	return unicode.IsLower(r)
+}
@@ -0,0 +1,66 @@
+package delta
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Kind tells what sort of change the hunk makes, as Changes tells it, except
+// that a hunk on its own can't be told to be a Move.
+func (h Hunk) Kind() Kind {
+	var del, ins []string
+	if h.Delete != "" {
+		del = strings.Fields(h.Delete)
+	}
+	if h.Insert != "" {
+		ins = strings.Fields(h.Insert)
+	}
+	return newOptions(false, nil).kind(del, ins, nil, nil)
+}
+
+// Severity rates how much the hunk likely matters, from 0 to 1, for review
+// tools to sort hunks by or hide the ones below a threshold. Hunks count
+// for more the more words they change, and when they change numbers, such
+// as amounts or dates, or names, taken to be the words written with a
+// capital which don't start a sentence. Changes to whitespace don't count,
+// and changes to punctuation, case or spelling count for little.
+func (h Hunk) Severity() float64 {
+	words := len(strings.Fields(h.Delete)) + len(strings.Fields(h.Insert))
+	score := 1 - 1/(1+float64(words)/5)
+	if strings.ContainsFunc(h.Delete+h.Insert, unicode.IsDigit) {
+		score += 0.4
+	}
+	if named(h.Before, h.Delete) || named(h.Before, h.Insert) {
+		score += 0.2
+	}
+
+	switch h.Kind() {
+	case Whitespace:
+		score = 0
+	case Punctuation, Case:
+		score *= 0.2
+	case Spelling:
+		score *= 0.3
+	}
+	return min(score, 1)
+}
+
+// named tells whether the text, following before, has a word written with a
+// capital which doesn't start a sentence.
+func named(before, text string) bool {
+	prev := strings.Fields(before)
+	last := ""
+	if len(prev) > 0 {
+		last = prev[len(prev)-1]
+	}
+	for _, w := range strings.Fields(text) {
+		r, _ := utf8.DecodeRuneInString(w)
+		end, _ := utf8.DecodeLastRuneInString(last)
+		if unicode.IsUpper(r) && last != "" && !strings.ContainsRune(".!?", end) {
+			return true
+		}
+		last = w
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package shingle
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math/bits"
+)
+
+// SimHash returns a 64 bit fingerprint of the shingles, which comes out the
+// same for the same set and differs in only a few bits for similar ones.
+// Compare fingerprints with Hamming.
+func SimHash(s Set) uint64 {
+	var votes [64]int
+	for h := range s {
+		h = mix(h)
+		for i := range votes {
+			if h&(1<<i) != 0 {
+				votes[i]++
+			} else {
+				votes[i]--
+			}
+		}
+	}
+
+	var f uint64
+	for i, v := range votes {
+		if v > 0 {
+			f |= 1 << i
+		}
+	}
+	return f
+}
+
+// Hamming returns the number of bits two SimHash fingerprints differ in. Near
+// duplicates tend to be within 3 bits of each other.
+func Hamming(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// Signature is a MinHash signature: for each of its hash functions, the
+// smallest hash of any shingle of the set.
+type Signature []uint64
+
+// MinHash returns the MinHash signature of the shingles, using k hash
+// functions. More of them estimate the Jaccard similarity more closely,
+// with an error of about 1/sqrt(k).
+func MinHash(s Set, k int) Signature {
+	sig := make(Signature, k)
+	for i := range sig {
+		sig[i] = ^uint64(0)
+	}
+	for h := range s {
+		for i := range sig {
+			if v := mix(h ^ seed(i)); v < sig[i] {
+				sig[i] = v
+			}
+		}
+	}
+	return sig
+}
+
+// Jaccard estimates the Resemblance of the sets the two signatures were made
+// of, which have to be of the same length.
+func Jaccard(a, b Signature) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	same := 0
+	for i := range a {
+		if a[i] == b[i] {
+			same++
+		}
+	}
+	return float64(same) / float64(len(a))
+}
+
+// Bands splits the signature into n bands and returns a key for each, for
+// bucketing documents by similarity: documents sharing any key are likely
+// similar, and only those need to be compared. Fewer, wider bands only let
+// the more similar documents through. The rows left over when the length
+// isn't a multiple of n are ignored.
+func (s Signature) Bands(n int) []uint64 {
+	if n <= 0 || n > len(s) {
+		return nil
+	}
+	rows := len(s) / n
+	keys := make([]uint64, n)
+	for band := range keys {
+		h := fnv.New64a()
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], uint64(band))
+		h.Write(b[:])
+		for _, v := range s[band*rows : (band+1)*rows] {
+			binary.BigEndian.PutUint64(b[:], v)
+			h.Write(b[:])
+		}
+		keys[band] = h.Sum64()
+	}
+	return keys
+}
+
+// seed returns the seed of the i-th MinHash function.
+func seed(i int) uint64 {
+	return mix(uint64(i) + 0x9e3779b97f4a7c15)
+}
+
+// mix scrambles the bits of x, the finalizer of SplitMix64.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
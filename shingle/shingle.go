@@ -0,0 +1,140 @@
+// Shingle finds near duplicates among documents by their shingles: every run
+// of w consecutive words. Documents sharing most of their shingles are
+// mostly the same text, which is far cheaper to find out than diffing them
+// word by word. An Index finds the documents of a corpus worth diffing a new
+// one against. For document sets too large to keep the shingles of, SimHash
+// and MinHash boil them down to fingerprints of a few bytes.
+//
+// Examples:
+//
+//	a := shingle.New("the quick brown fox jumps over the lazy dog", 3)
+//	b := shingle.New("the quick brown fox leaps over the lazy dog", 3)
+//	shingle.Resemblance(a, b)
+//		// 0.4
+package shingle
+
+import (
+	"hash/fnv"
+	"sort"
+	"strings"
+)
+
+// Set is the shingles of a document, each hashed to a number.
+type Set map[uint64]struct{}
+
+// New returns the set of w-shingles of the text. Words are split on
+// whitespace and compared in lowercase. Text of fewer than w words makes a
+// single shingle of all of them.
+func New(text string, w int) Set {
+	words := strings.Fields(strings.ToLower(text))
+	s := make(Set)
+	if len(words) == 0 {
+		return s
+	}
+
+	w = max(1, min(w, len(words)))
+	for i := 0; i+w <= len(words); i++ {
+		h := fnv.New64a()
+		for j, word := range words[i : i+w] {
+			if j > 0 {
+				h.Write([]byte{0})
+			}
+			h.Write([]byte(word))
+		}
+		s[h.Sum64()] = struct{}{}
+	}
+	return s
+}
+
+// common counts the shingles the two sets have in common.
+func common(a, b Set) int {
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+	n := 0
+	for h := range a {
+		if _, ok := b[h]; ok {
+			n++
+		}
+	}
+	return n
+}
+
+// Resemblance returns the Jaccard similarity of the two sets: the shingles
+// they share over all their shingles, from 0 to 1. Two empty sets resemble
+// each other completely.
+func Resemblance(a, b Set) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	n := common(a, b)
+	return float64(n) / float64(len(a)+len(b)-n)
+}
+
+// Containment returns how much of a is contained in b, from 0 to 1, which
+// tells whether a document was taken out of a longer one.
+func Containment(a, b Set) float64 {
+	if len(a) == 0 {
+		return 1
+	}
+	return float64(common(a, b)) / float64(len(a))
+}
+
+// Index holds the shingles of a corpus of documents, for finding the ones
+// resembling a given document without comparing it against all of them.
+type Index struct {
+	w        int
+	docs     []Set
+	postings map[uint64][]int
+}
+
+// NewIndex returns an empty index of w-shingles.
+func NewIndex(w int) *Index {
+	return &Index{w: w, postings: make(map[uint64][]int)}
+}
+
+// Add adds a document to the index and returns its number, starting from 0.
+func (x *Index) Add(text string) int {
+	id := len(x.docs)
+	s := New(text, x.w)
+	x.docs = append(x.docs, s)
+	for h := range s {
+		x.postings[h] = append(x.postings[h], id)
+	}
+	return id
+}
+
+// Match is a document of an index resembling the one looked for.
+type Match struct {
+	Doc         int
+	Resemblance float64
+}
+
+// Similar returns the documents of the index whose Resemblance to the text
+// is at least threshold, most resembling first. Only the documents sharing
+// a shingle with the text are looked at, so a threshold of 0 doesn't return
+// those which have nothing in common with it.
+func (x *Index) Similar(text string, threshold float64) []Match {
+	s := New(text, x.w)
+	shared := make(map[int]int)
+	for h := range s {
+		for _, id := range x.postings[h] {
+			shared[id]++
+		}
+	}
+
+	var matches []Match
+	for id, n := range shared {
+		r := float64(n) / float64(len(s)+len(x.docs[id])-n)
+		if r >= threshold {
+			matches = append(matches, Match{id, r})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Resemblance != matches[j].Resemblance {
+			return matches[i].Resemblance > matches[j].Resemblance
+		}
+		return matches[i].Doc < matches[j].Doc
+	})
+	return matches
+}
@@ -0,0 +1,80 @@
+package delta
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Policy tells which changes IsSignificant counts. Changes which only touch
+// whitespace never count.
+type Policy struct {
+	// IgnoreCase and IgnorePunctuation leave out changes which only touch
+	// case or punctuation.
+	IgnoreCase        bool
+	IgnorePunctuation bool
+	// MinWords is how many words have to change for the revisions to
+	// differ significantly, 1 when it's 0 or less. A word replaced by
+	// another counts once, and words given to WithStopwords don't count.
+	// WithDocumentFrequencies counts rarer words for more than common ones.
+	MinWords int
+	// Options are used for comparing the revisions, such as WithStemmer.
+	Options []Option
+}
+
+// IsSignificant tells whether curr differs enough from prev to be worth
+// telling anyone about, by the given policy, for watching pages and other
+// documents for changes.
+//
+//	delta.IsSignificant("Hello world", "hello, world", delta.Policy{IgnoreCase: true, IgnorePunctuation: true})
+//		// false
+func IsSignificant(prev, curr string, policy Policy) bool {
+	if prev == curr {
+		return false
+	}
+
+	o := newOptions(false, policy.Options)
+	edits := o.diff(o.tokenize(prev), o.tokenize(curr))
+
+	// What's left of the text once the changes to ignore are taken out.
+	rest := func(text string) string {
+		if policy.IgnoreCase {
+			text = strings.ToLower(text)
+		}
+		if policy.IgnorePunctuation {
+			return strip(text, isPunctOrSpace)
+		}
+		return strip(text, unicode.IsSpace)
+	}
+
+	changed := 0.0
+	for n := 0; n < len(edits); {
+		if edits[n].Op == Equal {
+			n++
+			continue
+		}
+
+		// A deletion and the insertion next to it are a single change.
+		var deleted, inserted []string
+		for ; n < len(edits) && edits[n].Op != Equal; n++ {
+			if edits[n].Op == Delete {
+				deleted = append(deleted, edits[n].Items...)
+			} else {
+				inserted = append(inserted, edits[n].Items...)
+			}
+		}
+		d, i := o.join(deleted), o.join(inserted)
+		if rest(d) != rest(i) {
+			changed += max(o.weigh(d), o.weigh(i))
+		}
+	}
+	return changed >= float64(max(policy.MinWords, 1))
+}
+
+// weigh adds up the weights of the words of the text.
+func (o *options) weigh(text string) float64 {
+	sum := 0.0
+	for _, w := range strings.Fields(text) {
+		sum += o.weight(w)
+	}
+	return sum
+}
@@ -0,0 +1,130 @@
+package delta
+
+// Similarity tells how much alike the two revisions are, from 0 for nothing
+// in common to 1 for the same words. It's the share of words the revisions
+// have in common: twice the unchanged words, over the words of both. Line
+// breaks and whitespace don't count, and options normalizing the words are
+// taken into account.
+//
+//	delta.Similarity("hello world", "hello earth")
+//		// 0.5
+func Similarity(prev, curr string, opts ...Option) float64 {
+	o := newOptions(false, opts)
+	return o.similarity(o.tokenize(prev), o.tokenize(curr))
+}
+
+// similarity compares the tokens for Similarity.
+func (o *options) similarity(prev, curr []string) float64 {
+	same, total := 0, 0
+	for _, e := range o.diff(prev, curr) {
+		for _, w := range e.Items {
+			if w == "" || o.blank(w) {
+				continue
+			}
+			total++
+			if e.Op == Equal {
+				same += 2
+				total++
+			}
+		}
+	}
+	if total == 0 {
+		return 1
+	}
+	return float64(same) / float64(total)
+}
+
+// Closest finds the candidate most similar to the target, such as the
+// stored revision a pasted text most likely derives from, so it can be
+// diffed against that one. It returns the index of the candidate along with
+// its Similarity, or -1 when there are no candidates. Of equally similar
+// candidates, the first one wins.
+func Closest(target string, candidates []string, opts ...Option) (index int, score float64) {
+	o := newOptions(false, opts)
+	t := o.tokenize(target)
+
+	index = -1
+	for i, c := range candidates {
+		if s := o.similarity(o.tokenize(c), t); index < 0 || s > score {
+			index, score = i, s
+		}
+	}
+	return index, score
+}
+
+// Similarities returns the Similarity of every pair of the documents, for
+// clustering them or finding duplicates: the similarity of the i-th and the
+// j-th document is at [i][j], as well as at [j][i]. Every document is split
+// and normalized only once, and each distinct token given a number, so the
+// comparisons only compare numbers, which is a lot faster than calling
+// Similarity for every pair.
+func Similarities(docs []string, opts ...Option) [][]float64 {
+	o := newOptions(false, opts)
+
+	// Reflowing compares groups of tokens rather than tokens, so each
+	// pair is left to similarity.
+	var tokens [][]string
+	if o.reflow {
+		for _, d := range docs {
+			tokens = append(tokens, o.tokenize(d))
+		}
+	}
+
+	ids := make(map[string]int32)
+	var blank []bool
+	seqs := make([][]int32, len(docs))
+	for i, d := range docs {
+		if o.reflow {
+			continue
+		}
+		for _, w := range o.tokenize(d) {
+			key := o.key(w)
+			id, ok := ids[key]
+			if !ok {
+				id = int32(len(blank))
+				ids[key] = id
+				blank = append(blank, w == "" || o.blank(w))
+			}
+			seqs[i] = append(seqs[i], id)
+		}
+	}
+
+	scores := make([][]float64, len(docs))
+	for i := range scores {
+		scores[i] = make([]float64, len(docs))
+	}
+	for i := range docs {
+		scores[i][i] = 1
+		for j := i + 1; j < len(docs); j++ {
+			if o.reflow {
+				scores[i][j] = o.similarity(tokens[i], tokens[j])
+			} else {
+				scores[i][j] = interned(seqs[i], seqs[j], blank)
+			}
+			scores[j][i] = scores[i][j]
+		}
+	}
+	return scores
+}
+
+// interned works out the similarity of two documents given as the numbers
+// of their tokens, like similarity does.
+func interned(prev, curr []int32, blank []bool) float64 {
+	same, total := 0, 0
+	for _, e := range DiffSlices(prev, curr) {
+		for _, id := range e.Items {
+			if blank[id] {
+				continue
+			}
+			total++
+			if e.Op == Equal {
+				same += 2
+				total++
+			}
+		}
+	}
+	if total == 0 {
+		return 1
+	}
+	return float64(same) / float64(total)
+}
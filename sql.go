@@ -0,0 +1,193 @@
+package delta
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SQL returns a tokenizer for SQL, splitting it into keywords and
+// identifiers, quoted identifiers, string literals, numbers, comments,
+// operators and punctuation, so "(id" and "id," compare as the same
+// identifier.
+//
+//	delta.Calculate("SELECT id,name FROM t", "SELECT id, email FROM t", true, delta.WithTokenizer(delta.SQL()))
+//		// "SELECT id,---name---+++ email+++ FROM t"
+func SQL() Tokenizer {
+	return func(text string) []string {
+		var tokens []string
+		for text != "" {
+			n := sqlToken(text)
+			tokens = appendLines(tokens, text[:n])
+			text = text[n:]
+		}
+		return tokens
+	}
+}
+
+// sqlOperators are the operators of more than one character.
+var sqlOperators = []string{"<=", ">=", "<>", "!=", "::", "||", "->>", "->", "=>"}
+
+// sqlToken returns the length of the token the text starts with.
+func sqlToken(text string) int {
+	r, size := utf8.DecodeRuneInString(text)
+	switch {
+	case r == '\n':
+		return 1
+	case unicode.IsSpace(r):
+		return len(text) - len(strings.TrimLeftFunc(text, func(r rune) bool {
+			return r != '\n' && unicode.IsSpace(r)
+		}))
+	case strings.HasPrefix(text, "--"):
+		if i := strings.IndexByte(text, '\n'); i >= 0 {
+			return i
+		}
+		return len(text)
+	case strings.HasPrefix(text, "/*"):
+		if i := strings.Index(text[2:], "*/"); i >= 0 {
+			return i + 4
+		}
+		return len(text)
+	case r == '\'' || r == '"' || r == '`':
+		// Quotes are escaped by doubling them.
+		for i := 1; i < len(text); i++ {
+			if text[i] == byte(r) {
+				if i+1 < len(text) && text[i+1] == byte(r) {
+					i++
+					continue
+				}
+				return i + 1
+			}
+		}
+		return len(text)
+	case unicode.IsDigit(r):
+		return len(text) - len(strings.TrimLeftFunc(text, func(r rune) bool {
+			return unicode.IsDigit(r) || r == '.'
+		}))
+	case isSQLWordRune(r):
+		return len(text) - len(strings.TrimLeftFunc(text, isSQLWordRune))
+	}
+	for _, op := range sqlOperators {
+		if strings.HasPrefix(text, op) {
+			return len(op)
+		}
+	}
+	return size
+}
+
+// isSQLWordRune tells whether the rune can be part of a keyword or an
+// identifier which isn't quoted.
+func isSQLWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '$'
+}
+
+// WithSQL compares SQL token by token, as split by SQL. Statements are
+// lined up first, by what they do to which table or other object, such as
+// "CREATE TABLE users", and only compared to their counterparts. Keywords
+// and identifiers which aren't quoted are compared regardless of case, as
+// databases do, and how the statements are laid out over lines and
+// indented doesn't count, so migration files compare on the changes to
+// their clauses.
+//
+//	delta.Calculate("create table t (id int, name text)", "CREATE TABLE t (\n  id bigint,\n  name text\n)", true, delta.WithSQL())
+//		// "CREATE TABLE t (\nid ---int---+++bigint+++,\nname text\n)"
+func WithSQL() Option {
+	return func(o *options) {
+		o.tokenizer = SQL()
+		o.reflow = true
+		o.statements = true
+		o.keys = append(o.keys, func(w string) string {
+			if r, _ := utf8.DecodeRuneInString(w); r != '$' && !unicode.IsDigit(r) && strings.IndexFunc(w, func(r rune) bool { return !isSQLWordRune(r) }) < 0 {
+				return strings.ToUpper(w)
+			}
+			return w
+		})
+	}
+}
+
+// sqlVerbs are the keywords statements start with up to the name of the
+// object they're about.
+var sqlVerbs = []string{
+	"CREATE", "ALTER", "DROP", "TRUNCATE", "RENAME", "COMMENT", "GRANT", "REVOKE",
+	"INSERT", "UPDATE", "DELETE", "INTO", "FROM", "ON", "OR", "REPLACE", "IF",
+	"NOT", "EXISTS", "UNIQUE", "TEMP", "TEMPORARY", "MATERIALIZED", "TABLE",
+	"VIEW", "INDEX", "SEQUENCE", "FUNCTION", "PROCEDURE", "TRIGGER", "TYPE",
+	"SCHEMA", "DATABASE", "EXTENSION", "DOMAIN", "COLUMN", "CONSTRAINT",
+}
+
+// byStatements lines up the statements of the sequences of keys by their
+// heads, then diffs the tokens of every statement with those of its
+// counterpart, and of the statements in between together.
+func (o *options) byStatements(prev, curr []string) []Edit[string] {
+	ps, cs := statements(prev), statements(curr)
+	ph, ch := make([]string, len(ps)), make([]string, len(cs))
+	for i, st := range ps {
+		ph[i] = head(st)
+	}
+	for j, st := range cs {
+		ch[j] = head(st)
+	}
+
+	var edits []Edit[string]
+	var deleted, inserted []string
+	flush := func() {
+		if len(deleted) > 0 || len(inserted) > 0 {
+			edits = appendEdits(edits, o.align(deleted, inserted)...)
+		}
+		deleted, inserted = nil, nil
+	}
+	i, j := 0, 0
+	for _, e := range DiffSlices(ph, ch) {
+		for range e.Items {
+			switch e.Op {
+			case Equal:
+				flush()
+				edits = appendEdits(edits, o.align(ps[i], cs[j])...)
+				i, j = i+1, j+1
+			case Delete:
+				deleted = append(deleted, ps[i]...)
+				i++
+			case Insert:
+				inserted = append(inserted, cs[j]...)
+				j++
+			}
+		}
+	}
+	flush()
+	return edits
+}
+
+// statements splits the keys into statements, each ending with its
+// semicolon.
+func statements(keys []string) [][]string {
+	var sts [][]string
+	start := 0
+	for i, k := range keys {
+		if k == ";" {
+			sts = append(sts, keys[start:i+1])
+			start = i + 1
+		}
+	}
+	if start < len(keys) {
+		sts = append(sts, keys[start:])
+	}
+	return sts
+}
+
+// head returns what a statement does to which object: its keywords up to
+// the first word which isn't one of sqlVerbs, which is taken to be the name.
+// Comments are left out.
+func head(statement []string) string {
+	var words []string
+	for _, k := range statement {
+		if strings.HasPrefix(k, "--") || strings.HasPrefix(k, "/*") {
+			continue
+		}
+		words = append(words, k)
+		if !slices.Contains(sqlVerbs, k) {
+			break
+		}
+	}
+	return strings.Join(words, " ")
+}
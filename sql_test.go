@@ -0,0 +1,27 @@
+package delta
+
+import "testing"
+
+func TestCombinedSQL(t *testing.T) {
+	tests := []struct {
+		base      string
+		revisions []Revision
+		want      string
+	}{
+		{
+			"the cat sat",
+			[]Revision{{"The cat, sat", "x"}, {"the cat sat z", "y"}},
+			"the cat +++, +++[x]sat+++ z+++[y]",
+		},
+		{
+			"select a from t",
+			[]Revision{{"SELECT a\nFROM t", "x"}, {"select b from t", "y"}},
+			"select ---a ---[y]+++b +++[y]from t",
+		},
+	}
+	for _, tt := range tests {
+		if got := Combined(tt.base, tt.revisions, true, WithSQL()); got != tt.want {
+			t.Errorf("Combined(%q, %q) = %q, want %q", tt.base, tt.revisions, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package delta
+
+import "strings"
+
+// English is a Stemmer for English, taking the inflections off words the way
+// the first step of the Porter stemmer does: plurals, past tenses and -ing
+// forms, so "running", "runs" and "run" all come out as "run". Words are
+// lowercased first.
+func English(word string) string {
+	w := strings.ToLower(word)
+	if len(w) <= 2 {
+		return w
+	}
+
+	// Plurals.
+	switch {
+	case strings.HasSuffix(w, "sses"), strings.HasSuffix(w, "ies"):
+		w = w[:len(w)-2]
+	case strings.HasSuffix(w, "ss"):
+	case strings.HasSuffix(w, "s"):
+		w = w[:len(w)-1]
+	}
+
+	// Past tenses and -ing forms.
+	trimmed := false
+	switch {
+	case strings.HasSuffix(w, "eed"):
+		if measure(w[:len(w)-3]) > 0 {
+			w = w[:len(w)-1]
+		}
+	case strings.HasSuffix(w, "ed") && vowel(w[:len(w)-2]):
+		w, trimmed = w[:len(w)-2], true
+	case strings.HasSuffix(w, "ing") && vowel(w[:len(w)-3]):
+		w, trimmed = w[:len(w)-3], true
+	}
+	if trimmed {
+		n := len(w)
+		switch {
+		case strings.HasSuffix(w, "at"), strings.HasSuffix(w, "bl"), strings.HasSuffix(w, "iz"):
+			w += "e"
+		case n >= 2 && w[n-1] == w[n-2] && consonant(w, n-1) && !strings.ContainsRune("lsz", rune(w[n-1])):
+			w = w[:n-1]
+		case measure(w) == 1 && short(w):
+			w += "e"
+		}
+	}
+
+	// A final y after a vowel in the stem.
+	if strings.HasSuffix(w, "y") && vowel(w[:len(w)-1]) {
+		w = w[:len(w)-1] + "i"
+	}
+
+	return w
+}
+
+// consonant tells whether the letter at i is a consonant. A y is one only at
+// the start or after a vowel.
+func consonant(w string, i int) bool {
+	switch w[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !consonant(w, i-1)
+	}
+	return true
+}
+
+// vowel tells whether the stem has a vowel in it.
+func vowel(w string) bool {
+	for i := range w {
+		if !consonant(w, i) {
+			return true
+		}
+	}
+	return false
+}
+
+// measure counts the vowel and consonant sequences in the stem, m in the
+// terms of Porter.
+func measure(w string) int {
+	m, i := 0, 0
+	for i < len(w) && consonant(w, i) {
+		i++
+	}
+	for i < len(w) {
+		for i < len(w) && !consonant(w, i) {
+			i++
+		}
+		if i == len(w) {
+			break
+		}
+		for i < len(w) && consonant(w, i) {
+			i++
+		}
+		m++
+	}
+	return m
+}
+
+// short tells whether the stem ends in a consonant, a vowel and a consonant
+// other than w, x or y, as in "hop".
+func short(w string) bool {
+	n := len(w)
+	return n >= 3 && consonant(w, n-3) && !consonant(w, n-2) && consonant(w, n-1) &&
+		!strings.ContainsRune("wxy", rune(w[n-1]))
+}
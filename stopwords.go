@@ -0,0 +1,55 @@
+package delta
+
+import "strings"
+
+// EnglishStopwords are the most common English function words, for
+// WithStopwords.
+var EnglishStopwords = []string{
+	"a", "an", "the", "and", "or", "but", "nor", "so", "of", "in", "on", "at",
+	"to", "for", "from", "by", "with", "as", "into", "about", "than", "then",
+	"is", "are", "was", "were", "be", "been", "being", "am", "do", "does",
+	"did", "has", "have", "had", "it", "its", "this", "that", "these",
+	"those", "there", "here", "which", "who", "whom", "what", "i", "you",
+	"he", "she", "we", "they", "me", "him", "her", "us", "them", "my",
+	"your", "his", "our", "their", "not", "no", "if", "very", "just",
+}
+
+// WithStopwords gives changes to the given words, such as the function
+// words of EnglishStopwords, no weight in IsSignificant and the Weight of
+// the Metrics, so "a" turning into "the" doesn't set off the same alerts as
+// a change of facts. Words are matched regardless of case and of the
+// punctuation around them.
+func WithStopwords(words []string) Option {
+	return func(o *options) {
+		if o.stopwords == nil {
+			o.stopwords = make(map[string]bool, len(words))
+		}
+		for _, w := range words {
+			o.stopwords[strings.ToLower(w)] = true
+		}
+	}
+}
+
+// weight returns how much a change of the word counts towards a change
+// being significant.
+func (o *options) weight(w string) float64 {
+	w = term(w)
+	if o.stopwords != nil && o.stopwords[w] {
+		return 0
+	}
+	return o.idf(w)
+}
+
+// changed adds up the weights of the words inserted and deleted.
+func (o *options) changed(changes []Edit[string]) float64 {
+	sum := 0.0
+	for _, e := range changes {
+		if e.Op == Equal {
+			continue
+		}
+		for _, w := range e.Items {
+			sum += o.weigh(w)
+		}
+	}
+	return sum
+}
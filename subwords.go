@@ -0,0 +1,74 @@
+package delta
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Subwords returns a tokenizer splitting text into words and punctuation
+// like WithPunctuation does, then splitting every word into its parts by the
+// given segmenter, such as Compounds, so a change to part of a long compound
+// word, as German, Finnish or Turkish make, only marks that part.
+//
+//	delta.Calculate("die Haustür", "die Autotür", true, delta.WithTokenizer(delta.Subwords(delta.Compounds([]string{"haus", "auto", "tür"}))))
+//		// "die ---Haus---+++Auto+++tür"
+func Subwords(segment Segmenter) Tokenizer {
+	return func(text string) []string {
+		var tokens []string
+		for _, t := range punctuation(text) {
+			if strings.ContainsFunc(t, unicode.IsLetter) {
+				tokens = append(tokens, segment(t)...)
+			} else {
+				tokens = append(tokens, t)
+			}
+		}
+		return tokens
+	}
+}
+
+// Compounds returns a segmenter splitting words made up of the given parts
+// into them, using as few parts as it takes. Parts are matched regardless of
+// case, since they're often capitalized on their own but not within a
+// compound. Linking letters, like the s of German, have to be given as parts
+// of their own. Words which can't be made up of the parts are left whole.
+func Compounds(parts []string) Segmenter {
+	known := make(map[string]bool, len(parts))
+	longest := 0
+	for _, p := range parts {
+		p = strings.ToLower(p)
+		known[p] = true
+		longest = max(longest, len([]rune(p)))
+	}
+
+	return func(word string) []string {
+		rs := []rune(word)
+		lower := []rune(strings.Map(unicode.ToLower, word))
+		if len(lower) != len(rs) {
+			return []string{word}
+		}
+
+		// fewest[i] is the fewest parts the first i letters are made
+		// of, and from[i] where the last of them starts.
+		fewest, from := make([]int, len(rs)+1), make([]int, len(rs)+1)
+		for i := 1; i <= len(rs); i++ {
+			fewest[i] = -1
+			for j := max(0, i-longest); j < i; j++ {
+				if fewest[j] >= 0 && known[string(lower[j:i])] && (fewest[i] < 0 || fewest[j]+1 < fewest[i]) {
+					fewest[i], from[i] = fewest[j]+1, j
+				}
+			}
+		}
+		if fewest[len(rs)] < 0 {
+			return []string{word}
+		}
+
+		var split []string
+		for i := len(rs); i > 0; i = from[i] {
+			split = append(split, string(rs[from[i]:i]))
+		}
+		for l, r := 0, len(split)-1; l < r; l, r = l+1, r-1 {
+			split[l], split[r] = split[r], split[l]
+		}
+		return split
+	}
+}
@@ -0,0 +1,177 @@
+package delta
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Color is a terminal color. The zero value leaves the terminal's own color
+// alone.
+type Color struct {
+	kind       colorKind
+	r, g, b, n uint8
+}
+
+type colorKind int
+
+const (
+	colorDefault colorKind = iota
+	colorBasic
+	colorPalette
+	colorRGB
+)
+
+// Basic returns one of the 16 standard terminal colors, 0 to 7 being the
+// normal ones and 8 to 15 their bright variants.
+func Basic(n uint8) Color {
+	return Color{kind: colorBasic, n: n % 16}
+}
+
+// Palette returns one of the 256 colors of the extended terminal palette.
+func Palette(n uint8) Color {
+	return Color{kind: colorPalette, n: n}
+}
+
+// RGB returns a 24-bit truecolor color.
+func RGB(r, g, b uint8) Color {
+	return Color{kind: colorRGB, r: r, g: g, b: b}
+}
+
+// sgr returns the SGR parameters selecting the color, as a foreground or a
+// background.
+func (c Color) sgr(background bool) string {
+	switch c.kind {
+	case colorBasic:
+		base := 30
+		if c.n >= 8 {
+			base = 90
+		}
+		if background {
+			base += 10
+		}
+		return strconv.Itoa(base + int(c.n%8))
+	case colorPalette:
+		if background {
+			return "48;5;" + strconv.Itoa(int(c.n))
+		}
+		return "38;5;" + strconv.Itoa(int(c.n))
+	case colorRGB:
+		p := "38;2;"
+		if background {
+			p = "48;2;"
+		}
+		return p + strconv.Itoa(int(c.r)) + ";" + strconv.Itoa(int(c.g)) + ";" + strconv.Itoa(int(c.b))
+	}
+	return ""
+}
+
+// Style is the look of a piece of terminal output.
+type Style struct {
+	Foreground Color
+	Background Color
+	Bold       bool
+	Underline  bool
+	Strike     bool
+}
+
+// start returns the escape sequence switching the terminal to the style, or
+// nothing for the zero style.
+func (s Style) start() string {
+	var params []string
+	if s.Bold {
+		params = append(params, "1")
+	}
+	if s.Underline {
+		params = append(params, "4")
+	}
+	if s.Strike {
+		params = append(params, "9")
+	}
+	if p := s.Foreground.sgr(false); p != "" {
+		params = append(params, p)
+	}
+	if p := s.Background.sgr(true); p != "" {
+		params = append(params, p)
+	}
+
+	if len(params) == 0 {
+		return ""
+	}
+	return "\x1b[" + strings.Join(params, ";") + "m"
+}
+
+// end returns the escape sequence resetting the terminal after the style.
+func (s Style) end() string {
+	if s.start() == "" {
+		return ""
+	}
+	return "\x1b[0m"
+}
+
+// Theme describes how changes look in the terminal. Insert and Delete style
+// the changed words, while InsertLine and DeleteLine style whole added and
+// removed lines in Unified, where the changed words inside a changed line
+// get Insert and Delete on top. A background on those makes the intra-line
+// changes stand out.
+type Theme struct {
+	Insert     Style
+	Delete     Style
+	InsertLine Style
+	DeleteLine Style
+}
+
+// Themes which work out of the box, for terminals with 16, 256 or true
+// colors respectively.
+var (
+	ThemeBasic = Theme{
+		Insert:     Style{Foreground: Basic(2), Bold: true},
+		Delete:     Style{Foreground: Basic(1), Strike: true},
+		InsertLine: Style{Foreground: Basic(2)},
+		DeleteLine: Style{Foreground: Basic(1)},
+	}
+	Theme256 = Theme{
+		Insert:     Style{Foreground: Palette(194), Background: Palette(28)},
+		Delete:     Style{Foreground: Palette(224), Background: Palette(124)},
+		InsertLine: Style{Foreground: Palette(114)},
+		DeleteLine: Style{Foreground: Palette(174)},
+	}
+	ThemeTruecolor = Theme{
+		Insert:     Style{Foreground: RGB(0xe6, 0xff, 0xed), Background: RGB(0x1f, 0x88, 0x3d)},
+		Delete:     Style{Foreground: RGB(0xff, 0xeb, 0xe9), Background: RGB(0xcf, 0x22, 0x2e)},
+		InsertLine: Style{Foreground: RGB(0x57, 0xab, 0x5a)},
+		DeleteLine: Style{Foreground: RGB(0xe5, 0x53, 0x4b)},
+	}
+)
+
+// WithTheme colors the plain text output of Calculate and Unified for the
+// terminal, using escape sequences instead of the +++ and --- markers.
+func WithTheme(theme Theme) Option {
+	return func(o *options) {
+		o.theme = &theme
+	}
+}
+
+// styled writes out a changed line for Unified, with the words changed by op
+// highlighted inside it.
+func styled(prefix, line string, changes []Edit[string], op Operation, o *options) string {
+	lineStyle, wordStyle := o.theme.InsertLine, o.theme.Insert
+	if op == Delete {
+		lineStyle, wordStyle = o.theme.DeleteLine, o.theme.Delete
+	}
+
+	var b strings.Builder
+	b.WriteString(lineStyle.start() + prefix + line[:len(line)-len(unindent(line))])
+	for n, ch := range only(changes, op) {
+		if n > 0 {
+			b.WriteString(o.space())
+		}
+		if ch.Op == Equal {
+			b.WriteString(o.mask(o.join(ch.Items)))
+			continue
+		}
+		b.WriteString(lineStyle.end() + wordStyle.start() + o.mask(o.join(ch.Items)) + wordStyle.end() + lineStyle.start())
+	}
+	b.WriteString(lineStyle.end())
+
+	return b.String()
+}
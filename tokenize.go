@@ -0,0 +1,293 @@
+package delta
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Tokenizer splits text into the tokens which get compared. Unlike the
+// words Calculate splits text into by default, the tokens have to add up to
+// the whole text again, whitespace included, since the output puts them back
+// together as they are. Line breaks should be tokens of their own, "\n", so
+// they are handled like they are without a tokenizer.
+type Tokenizer func(text string) []string
+
+// WithTokenizer replaces splitting the text on spaces with the given
+// tokenizer, for text which isn't made of words separated by spaces.
+func WithTokenizer(t Tokenizer) Option {
+	return func(o *options) {
+		o.tokenizer = t
+	}
+}
+
+// tokenize prepares the text and splits it using the tokenizer, or into
+// words when there is none.
+func (o *options) tokenize(text string) []string {
+	text = o.preprocess(text)
+	if o.tokenizer == nil {
+		return o.phrase(splitWords(text))
+	}
+	return o.phrase(o.tokenizer(text))
+}
+
+// Stage is a step the text goes through, either before it's split into
+// tokens, or as output once it's rendered. It's known by its name, so it can
+// be told apart from the others.
+type Stage struct {
+	Name  string
+	Apply func(text string) string
+}
+
+// WithStages changes the stages the text goes through before it's split
+// into tokens. The function is given the stages which would run, in order:
+// "newlines", turning every line ending into "\n", then "unwrap" with
+// WithUnwrap, and "trim", taking the whitespace off both ends but tabs. The
+// stages it returns run instead, so they can be reordered, left out, or
+// joined by stages of the caller's own. Since stages change the text as it's
+// shown as well, changes which should merely not count are better left to
+// options such as WithVolatile.
+//
+//	delta.WithStages(func(stages []delta.Stage) []delta.Stage {
+//		return append(stages, delta.Stage{Name: "lower", Apply: strings.ToLower})
+//	})
+func WithStages(edit func(stages []Stage) []Stage) Option {
+	return func(o *options) {
+		o.stages = chain(o.stages, edit)
+	}
+}
+
+// WithFilters changes the stages the output goes through once it's
+// rendered, like WithStages. The function is given the filters which would
+// run, in order: "markers", turning the tags into the markers of plain text
+// or the colors of a Theme, only there for plain text, then "newlines",
+// putting the line breaks back in, "unescape" for a Theme, and "trim". The
+// filters it returns run instead.
+//
+//	delta.WithFilters(func(filters []delta.Stage) []delta.Stage {
+//		return append(filters, delta.Stage{Name: "marks", Apply: strings.NewReplacer("<ins>", "<mark>", "</ins>", "</mark>").Replace})
+//	})
+func WithFilters(edit func(filters []Stage) []Stage) Option {
+	return func(o *options) {
+		o.filters = chain(o.filters, edit)
+	}
+}
+
+// chain makes an edit of stages doing the prior one, if any, then the next.
+func chain(prior, next func([]Stage) []Stage) func([]Stage) []Stage {
+	if prior == nil {
+		return next
+	}
+	return func(stages []Stage) []Stage {
+		return next(prior(stages))
+	}
+}
+
+// preprocess runs the text through the stages.
+func (o *options) preprocess(text string) string {
+	stages := []Stage{{"newlines", func(text string) string {
+		return regexpNewline.ReplaceAllString(text, "\n")
+	}}}
+	if o.unwrap {
+		stages = append(stages, Stage{"unwrap", unwrap})
+	}
+	stages = append(stages, Stage{"trim", trim})
+	if o.stages != nil {
+		stages = o.stages(stages)
+	}
+
+	for _, s := range stages {
+		text = s.Apply(text)
+	}
+	return text
+}
+
+// space returns what goes between two tokens in the output. Words get a
+// space, while the tokens of a tokenizer already hold their own whitespace.
+func (o *options) space() string {
+	if o.tokenizer != nil {
+		return ""
+	}
+	return " "
+}
+
+// blank tells whether the token is only there for the layout, so it doesn't
+// count as a word: line breaks, and whitespace between tokens.
+func (o *options) blank(w string) bool {
+	if w == tokenDouble || w == tokenSingle {
+		return true
+	}
+	return o.tokenizer != nil && strings.TrimSpace(w) == ""
+}
+
+// WithPunctuation splits the punctuation at the start and end of words into
+// tokens of its own, so "world." turning into "world" shows only the full stop
+// going, rather than the whole word being replaced. It replaces any other
+// tokenizer.
+func WithPunctuation() Option {
+	return WithTokenizer(punctuation)
+}
+
+// punctuation splits text on whitespace, then splits the punctuation off
+// both ends of the words.
+func punctuation(text string) []string {
+	var tokens []string
+	for len(text) > 0 {
+		r, _ := utf8.DecodeRuneInString(text)
+		var n int
+		switch {
+		case r == '\n':
+			n = 1
+		case unicode.IsSpace(r):
+			n = span(text, func(r rune) bool { return r != '\n' && unicode.IsSpace(r) })
+		case unicode.IsPunct(r):
+			n = span(text, unicode.IsPunct)
+		default:
+			n = span(text, func(r rune) bool { return !unicode.IsSpace(r) })
+			word := strings.TrimRightFunc(text[:n], unicode.IsPunct)
+			if word != "" {
+				n = len(word)
+			}
+		}
+		tokens = append(tokens, text[:n])
+		text = text[n:]
+	}
+	return tokens
+}
+
+// Pattern returns a tokenizer taking the matches of the regular expression
+// as tokens, instead of the words between spaces. Whatever is left between
+// two matches, like spaces or punctuation, becomes a token of its own. For
+// example, `[^\s/-]+` also splits words at hyphens and slashes, and
+// `[A-Z]*[a-z0-9]+|[A-Z]+` splits camelCase identifiers into their words.
+//
+//	delta.Calculate("read-only", "read-write", true, delta.WithTokenizer(delta.Pattern(regexp.MustCompile(`[^\s/-]+`))))
+//		// "read----only---+++write+++"
+func Pattern(re *regexp.Regexp) Tokenizer {
+	return func(text string) []string {
+		var tokens []string
+		last := 0
+		for _, m := range re.FindAllStringIndex(text, -1) {
+			if m[0] == m[1] {
+				continue
+			}
+			tokens = appendLines(tokens, text[last:m[0]])
+			tokens = appendLines(tokens, text[m[0]:m[1]])
+			last = m[1]
+		}
+		return appendLines(tokens, text[last:])
+	}
+}
+
+// appendLines appends the text as a token, with its line breaks split out
+// into tokens of their own.
+func appendLines(tokens []string, text string) []string {
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			tokens = append(tokens, "\n")
+		}
+		if line != "" {
+			tokens = append(tokens, line)
+		}
+	}
+	return tokens
+}
+
+// Segmenter splits a run of text written without spaces, such as Chinese or
+// Thai, into its words.
+type Segmenter func(run string) []string
+
+// CJK returns a tokenizer for Chinese and Japanese text, which doesn't put
+// spaces between its words. Runs of CJK characters are split into words by
+// the given segmenter, or into single characters when it's nil, which works
+// well enough since most characters carry a meaning of their own. CJK
+// punctuation always stands on its own, and anything else, such as Latin
+// words mixed in, is split on whitespace as usual.
+//
+//	delta.Calculate("我喜欢猫。", "我喜欢狗。", false, delta.WithTokenizer(delta.CJK(nil)))
+//		// "我喜欢<del>猫</del><ins>狗</ins>。"
+func CJK(segment Segmenter) Tokenizer {
+	return func(text string) []string {
+		var tokens []string
+		for len(text) > 0 {
+			r, _ := utf8.DecodeRuneInString(text)
+			var n int
+			switch {
+			case isCJKPunct(r):
+				_, n = utf8.DecodeRuneInString(text)
+			case isCJK(r):
+				n = span(text, isCJK)
+				if segment != nil {
+					tokens = append(tokens, segment(text[:n])...)
+					text = text[n:]
+					continue
+				}
+				_, n = utf8.DecodeRuneInString(text)
+			case r == '\n':
+				n = 1
+			case unicode.IsSpace(r):
+				n = span(text, func(r rune) bool { return r != '\n' && unicode.IsSpace(r) })
+			default:
+				n = span(text, func(r rune) bool { return !unicode.IsSpace(r) && !isCJK(r) && !isCJKPunct(r) })
+			}
+			tokens = append(tokens, text[:n])
+			text = text[n:]
+		}
+		return tokens
+	}
+}
+
+// Dictionary returns a segmenter splitting text into the given words,
+// always taking the longest word which fits. Characters which don't start
+// any of the words are taken one at a time, together with any marks on them.
+func Dictionary(words []string) Segmenter {
+	known := make(map[string]bool, len(words))
+	longest := 0
+	for _, w := range words {
+		known[w] = true
+		longest = max(longest, utf8.RuneCountInString(w))
+	}
+
+	return func(run string) []string {
+		var tokens []string
+		for len(run) > 0 {
+			_, first := utf8.DecodeRuneInString(run)
+			n := first + span(run[first:], func(r rune) bool { return unicode.In(r, unicode.Mn, unicode.Mc, unicode.Me) })
+			end, count := first, 1
+			for end < len(run) && count < longest {
+				_, size := utf8.DecodeRuneInString(run[end:])
+				end += size
+				count++
+				if known[run[:end]] {
+					n = end
+				}
+			}
+			tokens = append(tokens, run[:n])
+			run = run[n:]
+		}
+		return tokens
+	}
+}
+
+// span returns the length of the start of the text whose runes are all in.
+func span(text string, in func(rune) bool) int {
+	for i, r := range text {
+		if !in(r) {
+			return i
+		}
+	}
+	return len(text)
+}
+
+// isCJK tells whether the rune is written without spaces between words:
+// Chinese characters and the Japanese kana.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) || r == 'ー' || r == '々'
+}
+
+// isCJKPunct tells whether the rune is CJK punctuation or a full width form,
+// such as 。 or ，.
+func isCJKPunct(r rune) bool {
+	return (r >= 0x3000 && r <= 0x303f && r != '々') || (r >= 0xff00 && r <= 0xffef)
+}
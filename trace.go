@@ -0,0 +1,76 @@
+package delta
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// traceLimit is how many rows and columns of the matrix a trace shows at
+// most, since a matrix of long texts would be far too large to read.
+const traceLimit = 40
+
+// WithTrace writes what the algorithm went through to w: the tokens being
+// compared, the matrix of common subsequence lengths, the path backtracking
+// took through it, and the edits its steps were merged into. It's meant for
+// making sense of surprising output, and attaching to bug reports. When
+// tokens are compared by a normalized form, the trace shows the keys.
+func WithTrace(w io.Writer) Option {
+	return func(o *options) {
+		o.trace = w
+	}
+}
+
+// traceMatrix writes the tokens and the matrix built of them.
+func traceMatrix(w io.Writer, c matrix, prev, curr []string) {
+	fmt.Fprintf(w, "delta: comparing %d tokens to %d tokens\n", len(prev), len(curr))
+	if len(prev) == 0 || len(curr) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "delta: matrix, longest common subsequence of %d\n", c.at(len(prev)-1, len(curr)-1))
+
+	rows, cols := min(len(prev), traceLimit), min(len(curr), traceLimit)
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', tabwriter.AlignRight)
+	fmt.Fprint(tw, "\t")
+	for j := 0; j < cols; j++ {
+		fmt.Fprintf(tw, "%q\t", curr[j])
+	}
+	fmt.Fprintln(tw)
+	for i := 0; i < rows; i++ {
+		fmt.Fprintf(tw, "%q\t", prev[i])
+		for j := 0; j < cols; j++ {
+			fmt.Fprintf(tw, "%d\t", c.at(i, j))
+		}
+		fmt.Fprintln(tw)
+	}
+	tw.Flush()
+	if rows < len(prev) || cols < len(curr) {
+		fmt.Fprintf(w, "delta: matrix cut to its first %d rows and %d columns\n", rows, cols)
+	}
+}
+
+// tracePath writes the steps backtracking took, each with the indices of
+// the tokens it's at, then how they were merged into edits.
+func tracePath(w io.Writer, edits []Edit[string]) {
+	fmt.Fprintln(w, "delta: path")
+	i, j, steps := 0, 0, 0
+	for _, e := range edits {
+		for _, item := range e.Items {
+			fmt.Fprintf(w, "  %d,%d %s %q\n", i, j, e.Op, item)
+			switch e.Op {
+			case Equal:
+				i, j = i+1, j+1
+			case Delete:
+				i++
+			case Insert:
+				j++
+			}
+			steps++
+		}
+	}
+
+	fmt.Fprintf(w, "delta: merged %d steps into %d edits\n", steps, len(edits))
+	for _, e := range edits {
+		fmt.Fprintf(w, "  %s %q\n", e.Op, e.Items)
+	}
+}
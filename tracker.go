@@ -0,0 +1,121 @@
+package delta
+
+// Tracker keeps the diff of a text being edited against the revision it
+// started from up to date, for showing changes live while typing. Rather
+// than comparing the whole text again after every edit, only the part
+// around the edit is compared again, back to the unchanged words on either
+// side. The diff may come out a little longer than Calculate would make it
+// after many edits, but never wrong. A Tracker must not be used by several
+// goroutines at once.
+type Tracker struct {
+	plaintext bool
+	opts      []Option
+
+	text       string
+	prev, curr []string
+	edits      []Edit[string]
+}
+
+// NewTracker returns a Tracker of the changes made to prev so far, which
+// make up curr.
+func NewTracker(prev, curr string, plaintext bool, opts ...Option) *Tracker {
+	o := newOptions(plaintext, opts)
+	t := &Tracker{plaintext: plaintext, opts: opts, text: curr}
+	t.prev, t.curr = o.tokenize(prev), o.tokenize(curr)
+	t.edits = o.diff(t.prev, t.curr)
+	return t
+}
+
+// Text returns the current text, with all the edits made to it.
+func (t *Tracker) Text() string {
+	return t.text
+}
+
+// Edit replaces the n bytes of the current text starting at pos with text,
+// and updates the diff. Both pos and pos+n have to be within the current
+// text.
+func (t *Tracker) Edit(pos, n int, text string) {
+	t.text = t.text[:pos] + text + t.text[pos+n:]
+	o := newOptions(t.plaintext, t.opts)
+	curr := o.tokenize(t.text)
+
+	// With WithReflow the edits don't line up with the tokens of prev, so
+	// the whole text is compared again.
+	if o.reflow {
+		t.curr, t.edits = curr, o.diff(t.prev, curr)
+		return
+	}
+
+	// Only the tokens between the ones which are still the same at
+	// either end need comparing again.
+	head := 0
+	for head < len(curr) && head < len(t.curr) && curr[head] == t.curr[head] {
+		head++
+	}
+	tail := 0
+	for tail < len(curr)-head && tail < len(t.curr)-head && curr[len(curr)-1-tail] == t.curr[len(t.curr)-1-tail] {
+		tail++
+	}
+
+	// The window is widened to the unchanged tokens on either side, so
+	// changes next to the edit get compared again along with it.
+	type step struct {
+		op   Operation
+		item string
+	}
+	var steps []step
+	for _, e := range t.edits {
+		for _, item := range e.Items {
+			steps = append(steps, step{e.Op, item})
+		}
+	}
+	start, end := 0, len(steps)
+	pi, pj := 0, 0
+	i, j := 0, 0
+	for k, s := range steps {
+		if j <= head && (k == 0 || steps[k-1].op == Equal) {
+			start, pi, pj = k, i, j
+		}
+		if j >= len(t.curr)-tail && k >= start && s.op == Equal {
+			end = k
+			break
+		}
+		if s.op != Insert {
+			i++
+		}
+		if s.op != Delete {
+			j++
+		}
+	}
+	if end == len(steps) {
+		i, j = len(t.prev), len(t.curr)
+	}
+
+	var edits []Edit[string]
+	add := func(op Operation, items ...string) {
+		if len(items) == 0 {
+			return
+		}
+		if n := len(edits); n > 0 && edits[n-1].Op == op {
+			edits[n-1].Items = append(edits[n-1].Items, items...)
+			return
+		}
+		edits = append(edits, Edit[string]{op, append([]string(nil), items...)})
+	}
+	for _, s := range steps[:start] {
+		add(s.op, s.item)
+	}
+	for _, e := range o.diff(t.prev[pi:i], curr[pj:j+len(curr)-len(t.curr)]) {
+		add(e.Op, e.Items...)
+	}
+	for _, s := range steps[end:] {
+		add(s.op, s.item)
+	}
+
+	t.curr, t.edits = curr, edits
+}
+
+// Diff returns the diff of the current text, like Calculate.
+func (t *Tracker) Diff() string {
+	return newOptions(t.plaintext, t.opts).output(t.edits, t.prev, t.curr)
+}
@@ -0,0 +1,178 @@
+package delta
+
+import "strings"
+
+// Node is a node of the tree Tree returns. Type is "p" for paragraphs,
+// "ins", "del" and "mark" for changes, "br" for line breaks and "text" for
+// text, which is all a front end needs to turn it into elements of its own,
+// as React or Vue components do, rather than setting the HTML of Calculate
+// as the inner HTML of an element. Attrs holds the attributes the element
+// of a change would have in HTML, such as "class" and "data-author". Nodes
+// encode to JSON as {"type": "ins", "children": [...]}.
+type Node struct {
+	Type     string            `json:"type"`
+	Text     string            `json:"text,omitempty"`
+	Attrs    map[string]string `json:"attrs,omitempty"`
+	Children []Node            `json:"children,omitempty"`
+}
+
+// Tree compares the two revisions like Calculate, but returns the diff as a
+// tree of nodes, one for every paragraph, with the text unescaped.
+//
+//	delta.Tree("hello world", "hello earth")
+//		// []Node{{Type: "p", Children: []Node{
+//		// 	{Type: "text", Text: "hello "},
+//		// 	{Type: "del", Children: []Node{{Type: "text", Text: "world"}}},
+//		// 	{Type: "text", Text: " "},
+//		// 	{Type: "ins", Children: []Node{{Type: "text", Text: "earth"}}},
+//		// }}}
+func Tree(prev, curr string, opts ...Option) []Node {
+	o := newOptions(false, opts)
+	o.language(prev, curr)
+	o.granularity(prev, curr)
+	p, c := o.tokenize(prev), o.tokenize(curr)
+	changes := o.arrange(o.diff(p, c))
+	o.annotate(changes, p, c)
+	o.classify(changes)
+
+	t := &tree{}
+	corrected := -1
+	for n, ch := range changes {
+		if n == corrected {
+			continue
+		}
+		if ch.Op == Equal {
+			t.add(ch.Items, nil, o)
+			continue
+		}
+		commented := o.commented != nil && o.commented[n]
+		if o.ignoreBlank && breaksOnly(ch.Items, o) || o.hideComments && commented {
+			if ch.Op == Insert {
+				t.add(ch.Items, nil, o)
+			}
+			continue
+		}
+
+		change := &Node{Type: "ins"}
+		if ch.Op == Delete {
+			change.Type = "del"
+		}
+		var class []string
+		if commented {
+			class = append(class, "comment")
+		}
+		if o.kinds != nil && o.kinds[n] != "" {
+			class = append(class, o.kinds[n])
+		}
+		author := o.author
+		if o.authors != nil {
+			author = o.authors[n]
+		}
+		attrs := make(map[string]string)
+		if class != nil {
+			attrs["class"] = strings.Join(class, " ")
+		}
+		if author != "" {
+			attrs["data-author"] = author
+		}
+		for _, attr := range o.metadata {
+			attrs[attr.name] = attr.value
+		}
+		if old, new, ok := o.correction(changes, n); ok {
+			change.Type = "mark"
+			attrs["data-old"] = o.mask(o.join(old))
+			change.Attrs = attrs
+			t.add(new, change, o)
+			corrected = n + 1
+			continue
+		}
+		if len(attrs) > 0 {
+			change.Attrs = attrs
+		}
+		t.add(ch.Items, change, o)
+	}
+	return t.done()
+}
+
+// tree builds the nodes of Tree, paragraph by paragraph.
+type tree struct {
+	paragraphs []Node
+	current    []Node
+	space      bool
+}
+
+// add adds the tokens to the current paragraph, inside a copy of the
+// change when there is one, which is opened again in every paragraph the
+// tokens run into.
+func (t *tree) add(tokens []string, change *Node, o *options) {
+	var nodes []Node
+	flush := func() {
+		if len(nodes) == 0 {
+			return
+		}
+		if change == nil {
+			t.current = appendNodes(t.current, nodes...)
+		} else {
+			c := *change
+			c.Children = nodes
+			t.current = append(t.current, c)
+		}
+		nodes = nil
+	}
+
+	for _, w := range tokens {
+		switch {
+		case w == tokenDouble:
+			flush()
+			t.paragraph()
+			t.space = false
+			continue
+		case w == tokenSingle:
+			nodes = appendNodes(nodes, Node{Type: "br"})
+			t.space = false
+			continue
+		}
+		if t.space {
+			// The space between words goes inside a change only
+			// between its own words.
+			if len(nodes) > 0 || change == nil {
+				nodes = appendNodes(nodes, Node{Type: "text", Text: o.space()})
+			} else {
+				t.current = appendNodes(t.current, Node{Type: "text", Text: o.space()})
+			}
+		}
+		nodes = appendNodes(nodes, Node{Type: "text", Text: o.mask(w)})
+		t.space = true
+	}
+	flush()
+}
+
+// paragraph ends the current paragraph.
+func (t *tree) paragraph() {
+	t.paragraphs = append(t.paragraphs, Node{Type: "p", Children: t.current})
+	t.current = nil
+}
+
+// done ends the last paragraph and returns them all.
+func (t *tree) done() []Node {
+	t.paragraph()
+	return t.paragraphs
+}
+
+// appendNodes appends the nodes, merging text into the text before it and
+// dropping empty text.
+func appendNodes(nodes []Node, more ...Node) []Node {
+	for _, n := range more {
+		if n.Type == "text" {
+			if n.Text == "" {
+				continue
+			}
+			if last := len(nodes) - 1; last >= 0 && nodes[last].Type == "text" {
+				nodes[last].Text += n.Text
+				continue
+			}
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
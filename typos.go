@@ -0,0 +1,197 @@
+package delta
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+// WithTypos shows words replaced by words within the given edit distance,
+// 1 when it's 0 or less, as spelling fixes rather than changes of wording:
+// the corrected words are kept, marked with the words they replace. In HTML
+// that's a mark element of the class "spelling", with the old words in a
+// data-old attribute, and plain text has them between "~~~", as in
+// "~~~teh->the~~~", so copyedits can be told apart from deletions and
+// insertions. Swapping two letters next to each other counts as a single
+// edit, so "teh" turning into "the" is a fix, but words need more than
+// twice as many letters as the distance, so "is" turning into "as" isn't. A
+// replacement of several words is a fix when every word is one.
+//
+//	delta.Calculate("teh cat", "the cat", false, delta.WithTypos(1))
+//		// `<mark class="spelling" data-old="teh">the</mark> cat`
+//
+//	delta.Calculate("teh cat", "the cat", true, delta.WithTypos(1))
+//		// "~~~teh->the~~~ cat"
+func WithTypos(distance int) Option {
+	return func(o *options) {
+		o.typos = max(distance, 1)
+	}
+}
+
+// WithCaseChanges shows words which only changed case, like "web" turning
+// into "Web", as case changes rather than changes of wording, the way
+// WithTypos shows spelling fixes: a mark element of the class "case" in
+// HTML, and the old and new words between "^^^" in plain text, so style
+// edits can be told apart from deletions and insertions. Like WithTypos, a
+// replacement of several words is a case change when every word is one.
+//
+//	delta.Calculate("the web", "the Web", false, delta.WithCaseChanges())
+//		// `the <mark class="case" data-old="web">Web</mark>`
+//
+//	delta.Calculate("the web", "the Web", true, delta.WithCaseChanges())
+//		// "the ^^^web->Web^^^"
+func WithCaseChanges() Option {
+	return func(o *options) {
+		o.cases = true
+	}
+}
+
+// classify finds the replacements which are case changes or spelling fixes,
+// if asked to.
+func (o *options) classify(changes []Edit[string]) {
+	if !o.cases && o.typos == 0 {
+		return
+	}
+
+	o.kinds = make([]string, len(changes))
+	for n := 0; n+1 < len(changes); n++ {
+		a, b := changes[n], changes[n+1]
+		if a.Op == Equal || b.Op == Equal || a.Op == b.Op {
+			continue
+		}
+		kind := ""
+		switch {
+		case o.cases && o.pairs(a.Items, b.Items, strings.EqualFold):
+			kind = "case"
+		case o.typos > 0 && o.pairs(a.Items, b.Items, o.typo):
+			kind = "spelling"
+		}
+		if kind != "" {
+			o.kinds[n], o.kinds[n+1] = kind, kind
+		}
+	}
+}
+
+// corrections are the markers of the kinds of replacements shown as
+// corrections, which plain text puts around them.
+var corrections = map[string]string{
+	"case":     "^^^",
+	"spelling": "~~~",
+}
+
+// correction tells whether the change at n and the one after it are a
+// correction, returning the words it replaces and the ones it puts in.
+func (o *options) correction(changes []Edit[string], n int) (old, new []string, ok bool) {
+	if o.kinds == nil || n+1 >= len(changes) || corrections[o.kinds[n]] == "" || o.kinds[n+1] != o.kinds[n] {
+		return nil, nil, false
+	}
+	old, new = changes[n].Items, changes[n+1].Items
+	if changes[n].Op == Insert {
+		old, new = new, old
+	}
+	return old, new, true
+}
+
+// correct returns a correction of the given kind, as shown in the output.
+func (o *options) correct(old, new []string, kind string, class []string, author string) string {
+	text := func(words []string) string {
+		var b strings.Builder
+		for i, w := range words {
+			if i > 0 {
+				b.WriteString(o.space())
+			}
+			b.WriteString(word(w, o))
+		}
+		return b.String()
+	}
+	if o.plaintext {
+		m := corrections[kind]
+		return m + text(old) + "->" + text(new) + m + o.signature(author) + o.space()
+	}
+
+	_, _, tag := o.tags()
+	var b strings.Builder
+	b.WriteString("<" + tag)
+	if o.bidi {
+		b.WriteString(` dir="auto"`)
+	}
+	b.WriteString(` class="` + strings.Join(class, " ") + `"`)
+	if author != "" {
+		b.WriteString(` data-author="` + html.EscapeString(author) + `"`)
+	}
+	for _, attr := range o.metadata {
+		b.WriteString(" " + attr.name + `="` + html.EscapeString(attr.value) + `"`)
+	}
+	b.WriteString(` data-old="` + html.EscapeString(o.mask(o.join(old))) + `">`)
+	b.WriteString(text(new) + "</" + tag + ">" + o.space())
+	return b.String()
+}
+
+// typo tells whether the words are a spelling fix of one another, within
+// the distance given to WithTypos, or 1 without it.
+func (o *options) typo(a, b string) bool {
+	typos := max(o.typos, 1)
+	return min(utf8.RuneCountInString(a), utf8.RuneCountInString(b)) > 2*typos &&
+		distance(a, b, typos) <= typos
+}
+
+// pairs tells whether the words of both runs, leaving out the line breaks
+// and whitespace, pair up one to one, with every pair as given.
+func (o *options) pairs(prev, curr []string, pair func(a, b string) bool) bool {
+	var p, c []string
+	for _, w := range prev {
+		if w != "" && !o.blank(w) {
+			p = append(p, w)
+		}
+	}
+	for _, w := range curr {
+		if w != "" && !o.blank(w) {
+			c = append(c, w)
+		}
+	}
+	if len(p) == 0 || len(p) != len(c) {
+		return false
+	}
+	for i := range p {
+		if !pair(p[i], c[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// distance returns the number of letters which have to be inserted, deleted,
+// replaced, or swapped with the one next to it, to turn a into b, or more
+// than limit once it's sure to be more.
+func distance(a, b string, limit int) int {
+	r, s := []rune(a), []rune(b)
+	if abs(len(r)-len(s)) > limit {
+		return limit + 1
+	}
+
+	// Three rows of the matrix are all it takes, the last two for swaps.
+	before, last, row := make([]int, len(s)+1), make([]int, len(s)+1), make([]int, len(s)+1)
+	for j := range last {
+		last[j] = j
+	}
+	for i := 1; i <= len(r); i++ {
+		row[0] = i
+		least := row[0]
+		for j := 1; j <= len(s); j++ {
+			cost := 1
+			if r[i-1] == s[j-1] {
+				cost = 0
+			}
+			row[j] = min(last[j]+1, row[j-1]+1, last[j-1]+cost)
+			if i > 1 && j > 1 && r[i-1] == s[j-2] && r[i-2] == s[j-1] {
+				row[j] = min(row[j], before[j-2]+1)
+			}
+			least = min(least, row[j])
+		}
+		if least > limit {
+			return limit + 1
+		}
+		before, last, row = last, row, before
+	}
+	return last[len(s)]
+}
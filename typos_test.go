@@ -0,0 +1,43 @@
+package delta
+
+import "testing"
+
+func TestTypos(t *testing.T) {
+	tests := []struct {
+		prev, curr string
+		plaintext  bool
+		want       string
+	}{
+		{"teh world", "the world", true, "~~~teh->the~~~ world"},
+		{"teh world", "the world", false, `<mark class="spelling" data-old="teh">the</mark> world`},
+		{"teh wrold", "the world", true, "~~~teh wrold->the world~~~"},
+		{"the cat", "the dog", true, "the ---cat--- +++dog+++"},
+	}
+	for _, tt := range tests {
+		got := Calculate(tt.prev, tt.curr, tt.plaintext, WithTypos(1))
+		if got != tt.want {
+			t.Errorf("Calculate(%q, %q, %v) = %q, want %q", tt.prev, tt.curr, tt.plaintext, got, tt.want)
+		}
+	}
+
+	if got := Calculate("teh world", "the world", true); got != "---teh--- +++the+++ world" {
+		t.Errorf("Calculate without WithTypos = %q, want a deletion and an insertion", got)
+	}
+}
+
+func TestCaseChanges(t *testing.T) {
+	tests := []struct {
+		prev, curr string
+		plaintext  bool
+		want       string
+	}{
+		{"Hello world", "hello world", true, "^^^Hello->hello^^^ world"},
+		{"Hello world", "hello world", false, `<mark class="case" data-old="Hello">hello</mark> world`},
+		{"the web", "the Web", false, `the <mark class="case" data-old="web">Web</mark>`},
+	}
+	for _, tt := range tests {
+		if got := Calculate(tt.prev, tt.curr, tt.plaintext, WithCaseChanges()); got != tt.want {
+			t.Errorf("Calculate(%q, %q, %v) = %q, want %q", tt.prev, tt.curr, tt.plaintext, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package vcdiff
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"hash/adler32"
+	"io"
+)
+
+// maxEncodingSize is the size of the largest delta encoding of a window
+// the decoder accepts. No instruction takes more than six bytes for every
+// byte of the window it produces, and the rest of the encoding is a lot
+// less than that.
+const maxEncodingSize = 8 * MaxWindowSize
+
+// Decoder reads a delta and returns the target it turns the source into.
+// Windows are decoded one at a time as the delta is read. Since windows may
+// copy from any of the target decoded before them, the target is kept in
+// memory as it grows.
+type Decoder struct {
+	r       *bufio.Reader
+	source  []byte
+	target  []byte
+	pending []byte
+	started bool
+	err     error
+}
+
+// NewDecoder returns a decoder reading the delta from r and applying it to
+// the source.
+func NewDecoder(r io.Reader, source []byte) *Decoder {
+	return &Decoder{r: bufio.NewReader(r), source: source}
+}
+
+// Decode applies the delta to the source and returns the target.
+func Decode(source, delta []byte) ([]byte, error) {
+	return io.ReadAll(NewDecoder(bytes.NewReader(delta), source))
+}
+
+// Read reads the decoded target.
+func (d *Decoder) Read(p []byte) (int, error) {
+	for len(d.pending) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		d.pending, d.err = d.window()
+	}
+
+	n := copy(p, d.pending)
+	d.pending = d.pending[n:]
+	return n, nil
+}
+
+// header reads the header of the delta.
+func (d *Decoder) header() error {
+	head := make([]byte, len(magic)+1)
+	if _, err := io.ReadFull(d.r, head); err != nil {
+		return ErrCorrupt
+	}
+	if !bytes.Equal(head[:3], magic[:3]) {
+		return ErrCorrupt
+	}
+	if head[3] != magic[3] {
+		return fmt.Errorf("%w: version %d", ErrUnsupported, head[3])
+	}
+
+	indicator := head[4]
+	switch {
+	case indicator&hdrDecompress != 0:
+		return fmt.Errorf("%w: secondary compression", ErrUnsupported)
+	case indicator&hdrCodeTable != 0:
+		return fmt.Errorf("%w: custom code table", ErrUnsupported)
+	case indicator&^(hdrDecompress|hdrCodeTable|hdrAppHeader) != 0:
+		return ErrCorrupt
+	}
+
+	// Application headers, written by xdelta, mean nothing to us.
+	if indicator&hdrAppHeader != 0 {
+		n, err := readVarint(d.r.ReadByte)
+		if err != nil {
+			return err
+		}
+		if _, err := d.r.Discard(n); err != nil {
+			return ErrCorrupt
+		}
+	}
+
+	return nil
+}
+
+// window decodes the next window, returning io.EOF once there are none.
+func (d *Decoder) window() ([]byte, error) {
+	if !d.started {
+		if err := d.header(); err != nil {
+			return nil, err
+		}
+		d.started = true
+	}
+
+	indicator, err := d.r.ReadByte()
+	if err == io.EOF {
+		return nil, io.EOF
+	} else if err != nil {
+		return nil, err
+	}
+	if indicator&^(winSource|winTarget|winChecksum) != 0 || indicator&winSource != 0 && indicator&winTarget != 0 {
+		return nil, ErrCorrupt
+	}
+
+	var segment []byte
+	if indicator&(winSource|winTarget) != 0 {
+		size, err := readVarint(d.r.ReadByte)
+		if err != nil {
+			return nil, err
+		}
+		pos, err := readVarint(d.r.ReadByte)
+		if err != nil {
+			return nil, err
+		}
+
+		from := d.source
+		if indicator&winTarget != 0 {
+			from = d.target
+		}
+		if pos+size > len(from) || pos+size < pos {
+			return nil, ErrCorrupt
+		}
+		segment = from[pos : pos+size]
+	}
+
+	// The length of the delta encoding comes from the delta itself, so
+	// it's read as it comes rather than allocated up front.
+	n, err := readVarint(d.r.ReadByte)
+	if err != nil {
+		return nil, err
+	}
+	if n > maxEncodingSize {
+		return nil, ErrCorrupt
+	}
+	var delta bytes.Buffer
+	if m, err := delta.ReadFrom(io.LimitReader(d.r, int64(n))); err != nil {
+		return nil, err
+	} else if m != int64(n) {
+		return nil, ErrCorrupt
+	}
+
+	target, err := apply(segment, delta.Bytes(), indicator&winChecksum != 0)
+	if err != nil {
+		return nil, err
+	}
+	d.target = append(d.target, target...)
+	return target, nil
+}
+
+// apply decodes the delta encoding of a window against its source segment.
+func apply(segment, delta []byte, checksum bool) ([]byte, error) {
+	r := &reader{b: delta}
+
+	size, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	if size > MaxWindowSize {
+		return nil, ErrCorrupt
+	}
+	indicator, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	if indicator != 0 {
+		return nil, fmt.Errorf("%w: compressed sections", ErrUnsupported)
+	}
+
+	var lens [3]int
+	for i := range lens {
+		if lens[i], err = r.varint(); err != nil {
+			return nil, err
+		}
+	}
+
+	var sum []byte
+	if checksum {
+		if sum, err = r.bytes(4); err != nil {
+			return nil, err
+		}
+	}
+
+	var sections [3]reader
+	for i := range sections {
+		b, err := r.bytes(lens[i])
+		if err != nil {
+			return nil, err
+		}
+		sections[i].b = b
+	}
+	data, inst, addrs := &sections[0], &sections[1], &sections[2]
+	if len(r.b) != 0 {
+		return nil, ErrCorrupt
+	}
+
+	// Addresses point into the source segment followed by the target
+	// window, which is built right behind it.
+	u := make([]byte, len(segment), len(segment)+size)
+	copy(u, segment)
+
+	var c cache
+	for len(inst.b) > 0 {
+		op, _ := inst.byte()
+		entry := codes[op]
+
+		for _, in := range [2][3]byte{{entry.typ1, entry.size1, entry.mode1}, {entry.typ2, entry.size2, entry.mode2}} {
+			typ, n := in[0], int(in[1])
+			if typ == noop {
+				continue
+			}
+			if n == 0 {
+				if n, err = inst.varint(); err != nil {
+					return nil, err
+				}
+			}
+			if len(u)+n > len(segment)+size {
+				return nil, ErrCorrupt
+			}
+
+			switch typ {
+			case add:
+				b, err := data.bytes(n)
+				if err != nil {
+					return nil, err
+				}
+				u = append(u, b...)
+			case run:
+				b, err := data.byte()
+				if err != nil {
+					return nil, err
+				}
+				for i := 0; i < n; i++ {
+					u = append(u, b)
+				}
+			case cp:
+				addr, err := c.decode(in[2], len(u), addrs)
+				if err != nil {
+					return nil, err
+				}
+				// Copies may overlap the bytes they produce,
+				// so they have to go one byte at a time.
+				for i := 0; i < n; i++ {
+					u = append(u, u[addr+i])
+				}
+			}
+		}
+	}
+
+	target := u[len(segment):]
+	if len(target) != size || len(data.b) != 0 || len(addrs.b) != 0 {
+		return nil, ErrCorrupt
+	}
+	if checksum {
+		want := uint32(sum[0])<<24 | uint32(sum[1])<<16 | uint32(sum[2])<<8 | uint32(sum[3])
+		if adler32.Checksum(target) != want {
+			return nil, ErrChecksum
+		}
+	}
+	return target, nil
+}
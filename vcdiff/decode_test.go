@@ -0,0 +1,30 @@
+package vcdiff
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	source := []byte("the quick brown fox jumps over the lazy dog")
+	target := []byte("the quick brown cat jumps over the lazy dog, twice")
+	got, err := Decode(source, Encode(source, target))
+	if err != nil || !bytes.Equal(got, target) {
+		t.Errorf("Decode = %q, %v, want %q", got, err, target)
+	}
+}
+
+func TestDecodeCorrupt(t *testing.T) {
+	head := []byte{0xd6, 0xc3, 0xc4, 0x00, 0x00, 0x00}
+	tests := map[string][]byte{
+		"huge encoding":  append(head, 0xff, 0xff, 0xff, 0xff, 0x7f),
+		"short encoding": append(head, 0x10, 0x01),
+		"huge window":    append(head, 0x06, 0xff, 0xff, 0xff, 0xff, 0x7f, 0x00),
+	}
+	for name, delta := range tests {
+		if _, err := Decode(nil, delta); !errors.Is(err, ErrCorrupt) {
+			t.Errorf("%s: Decode = %v, want ErrCorrupt", name, err)
+		}
+	}
+}
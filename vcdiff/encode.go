@@ -0,0 +1,353 @@
+package vcdiff
+
+import (
+	"bytes"
+	"hash/adler32"
+	"io"
+)
+
+// Tuning of the encoder. Matches shorter than minMatch can't be written with
+// the default code table any cheaper than adding the bytes, and following
+// more than maxChain candidates rarely finds anything better.
+const (
+	minMatch = 4
+	maxChain = 32
+
+	// DefaultWindowSize is the size of the target windows the encoder
+	// cuts its input into unless told otherwise.
+	DefaultWindowSize = 1 << 20
+
+	// MaxWindowSize is the size of the largest target windows the
+	// decoder accepts, which is what open-vcdiff allows as well. Larger
+	// window sizes given to the encoder are cut down to it.
+	MaxWindowSize = 1 << 26
+)
+
+// Encoder writes the delta between a source and the data written to it.
+// Data is buffered and encoded one target window at a time, so the delta is
+// written out while the data is still coming in. Close must be called to
+// flush the last window.
+type Encoder struct {
+	// WindowSize is the size of the target windows, DefaultWindowSize
+	// when zero and MaxWindowSize at most. Larger windows find more
+	// matches within the target.
+	WindowSize int
+
+	// Checksum stores an Adler-32 checksum of every target window, the
+	// way xdelta3 does. Decoders not knowing about it, or expecting the
+	// checksum of open-vcdiff, will reject the delta.
+	Checksum bool
+
+	w       io.Writer
+	source  []byte
+	index   *index
+	buf     []byte
+	started bool
+	err     error
+}
+
+// NewEncoder returns an encoder writing the delta from source to w.
+func NewEncoder(w io.Writer, source []byte) *Encoder {
+	return &Encoder{w: w, source: source}
+}
+
+// Encode returns the delta turning source into target.
+func Encode(source, target []byte) []byte {
+	var b bytes.Buffer
+	e := NewEncoder(&b, source)
+	e.Write(target)
+	e.Close()
+	return b.Bytes()
+}
+
+// Write buffers p, writing out the windows which got full.
+func (e *Encoder) Write(p []byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+
+	size := e.WindowSize
+	if size <= 0 {
+		size = DefaultWindowSize
+	}
+	size = min(size, MaxWindowSize)
+
+	e.buf = append(e.buf, p...)
+	for len(e.buf) >= size {
+		if e.err = e.window(e.buf[:size]); e.err != nil {
+			return 0, e.err
+		}
+		e.buf = e.buf[size:]
+	}
+	return len(p), nil
+}
+
+// Close writes out the last window. A delta for empty data still gets its
+// header, so it can be told apart from no delta at all.
+func (e *Encoder) Close() error {
+	if e.err != nil {
+		return e.err
+	}
+	if len(e.buf) > 0 || !e.started {
+		e.err = e.window(e.buf)
+	}
+	e.buf = nil
+	return e.err
+}
+
+// instruction is a single instruction of a window, before it's encoded.
+type instruction struct {
+	typ  byte
+	size int
+	addr int
+}
+
+// window encodes a single target window against the whole source.
+func (e *Encoder) window(target []byte) error {
+	if !e.started {
+		if _, err := e.w.Write(append(magic, 0)); err != nil {
+			return err
+		}
+		if e.index == nil {
+			e.index = newIndex(e.source, true)
+		}
+		e.started = true
+	}
+
+	ins := e.match(target)
+
+	var data []byte
+	for _, in := range ins {
+		switch in.typ {
+		case add:
+			data = append(data, target[in.addr:in.addr+in.size]...)
+		case run:
+			data = append(data, target[in.addr])
+		}
+	}
+	inst, addrs := e.instructions(ins)
+
+	var delta []byte
+	delta = appendVarint(delta, len(target))
+	delta = append(delta, 0)
+	delta = appendVarint(delta, len(data))
+	delta = appendVarint(delta, len(inst))
+	delta = appendVarint(delta, len(addrs))
+	if e.Checksum {
+		sum := adler32.Checksum(target)
+		delta = append(delta, byte(sum>>24), byte(sum>>16), byte(sum>>8), byte(sum))
+	}
+	delta = append(delta, data...)
+	delta = append(delta, inst...)
+	delta = append(delta, addrs...)
+
+	var head []byte
+	indicator := byte(0)
+	if len(e.source) > 0 {
+		indicator |= winSource
+	}
+	if e.Checksum {
+		indicator |= winChecksum
+	}
+	head = append(head, indicator)
+	if len(e.source) > 0 {
+		head = appendVarint(head, len(e.source))
+		head = appendVarint(head, 0)
+	}
+	head = appendVarint(head, len(delta))
+
+	if _, err := e.w.Write(head); err != nil {
+		return err
+	}
+	_, err := e.w.Write(delta)
+	return err
+}
+
+// match finds the instructions producing the target window. Copies come from
+// the source or from the part of the window already produced, addressed as
+// if the two were joined together. For adds and runs, addr is the position
+// in the window where their data is.
+func (e *Encoder) match(target []byte) []instruction {
+	var ins []instruction
+	src := len(e.source)
+	self := newIndex(target, false)
+
+	pending := 0
+	flush := func(t int) {
+		if t > pending {
+			ins = append(ins, instruction{typ: add, size: t - pending, addr: pending})
+		}
+	}
+
+	for t := 0; t < len(target); {
+		runLen := 1
+		for t+runLen < len(target) && target[t+runLen] == target[t] {
+			runLen++
+		}
+
+		addr, size := e.index.longest(e.source, target, t, len(e.source))
+		if a, s := self.longest(target, target, t, t); s > size {
+			addr, size = src+a, s
+		}
+
+		switch {
+		case runLen >= minMatch && runLen >= size:
+			flush(t)
+			ins = append(ins, instruction{typ: run, size: runLen, addr: t})
+			size = runLen
+		case size >= minMatch:
+			flush(t)
+			ins = append(ins, instruction{typ: cp, size: size, addr: addr})
+		default:
+			size = 1
+		}
+
+		for end := t + size; t < end; t++ {
+			self.insert(target, t)
+		}
+		if size >= minMatch {
+			pending = t
+		}
+	}
+	flush(len(target))
+
+	return ins
+}
+
+// encoded is an instruction along with the way its address is written, if
+// it's a copy.
+type encoded struct {
+	instruction
+	mode  byte
+	value int
+	same  bool
+}
+
+// instructions encodes the instructions of a window into the instruction and
+// address sections, pairing them up into single codes where the default
+// code table allows.
+func (e *Encoder) instructions(ins []instruction) (inst, addrs []byte) {
+	var c cache
+	here := len(e.source)
+
+	// Copies get their mode as they're encoded, since it depends on
+	// the state of the address cache.
+	enc := make([]encoded, len(ins))
+	for i, in := range ins {
+		enc[i].instruction = in
+		if in.typ == cp {
+			enc[i].mode, enc[i].value, enc[i].same = c.encode(in.addr, here)
+			c.update(in.addr)
+		}
+		here += in.size
+	}
+
+	for i := 0; i < len(enc); i++ {
+		in := enc[i]
+
+		if i+1 < len(enc) {
+			next := enc[i+1]
+			if op, ok := opcodes[code{in.typ, in.small(), in.mode, next.typ, next.small(), next.mode}]; ok && in.typ != run {
+				inst = append(inst, op)
+				addrs = in.appendAddress(addrs)
+				addrs = next.appendAddress(addrs)
+				i++
+				continue
+			}
+		}
+
+		if op, ok := opcodes[code{typ1: in.typ, size1: in.small(), mode1: in.mode}]; ok && in.small() != 0 && in.typ != run {
+			inst = append(inst, op)
+		} else {
+			inst = append(inst, opcodes[code{typ1: in.typ, mode1: in.mode}])
+			inst = appendVarint(inst, in.size)
+		}
+		addrs = in.appendAddress(addrs)
+	}
+
+	return inst, addrs
+}
+
+// small returns the size of the instruction if it fits in the code table,
+// or 0 for one which has to be written after the code.
+func (in encoded) small() byte {
+	if in.size > 255 {
+		return 0
+	}
+	return byte(in.size)
+}
+
+// appendAddress writes out the address of a copy, if the instruction is one.
+func (in encoded) appendAddress(b []byte) []byte {
+	switch {
+	case in.typ != cp:
+		return b
+	case in.same:
+		return append(b, byte(in.value))
+	}
+	return appendVarint(b, in.value)
+}
+
+// index finds earlier occurrences of the bytes at a position, using a hash
+// of the minMatch bytes starting there. Positions with the same hash are
+// chained from the most recent one, head and next holding one past the
+// position so that zero can end the chain.
+type index struct {
+	head  []int
+	next  []int
+	shift uint
+}
+
+// newIndex returns an index over data, filled with every position right away
+// when asked to, or left for the positions to be inserted one by one.
+func newIndex(data []byte, fill bool) *index {
+	bits := uint(10)
+	for bits < 22 && 1<<bits < len(data) {
+		bits++
+	}
+
+	x := &index{head: make([]int, 1<<bits), next: make([]int, len(data)), shift: 32 - bits}
+	for i := 0; fill && i+minMatch <= len(data); i++ {
+		x.insert(data, i)
+	}
+	return x
+}
+
+// insert adds a position to the index.
+func (x *index) insert(data []byte, i int) {
+	if i+minMatch > len(data) {
+		return
+	}
+	h := x.hash(data[i:])
+	x.next[i] = x.head[h]
+	x.head[h] = i + 1
+}
+
+// longest returns the position in data of the longest match for target at
+// t, looking only at the positions before limit.
+func (x *index) longest(data, target []byte, t, limit int) (addr, size int) {
+	if t+minMatch > len(target) {
+		return 0, 0
+	}
+
+	i := x.head[x.hash(target[t:])]
+	for chain := 0; i > 0 && chain < maxChain; chain++ {
+		if p := i - 1; p < limit {
+			n := 0
+			for p+n < len(data) && t+n < len(target) && data[p+n] == target[t+n] {
+				n++
+			}
+			if n > size {
+				addr, size = p, n
+			}
+		}
+		i = x.next[i-1]
+	}
+
+	return addr, size
+}
+
+// hash mixes the first minMatch bytes of b into a bucket of the index.
+func (x *index) hash(b []byte) uint32 {
+	return (uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24) * 2654435761 >> x.shift
+}
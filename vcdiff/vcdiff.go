@@ -0,0 +1,284 @@
+// Vcdiff is an encoder and decoder for VCDIFF (RFC 3284), the generic format
+// for binary deltas. Where delta shows people what changed in a text, vcdiff
+// ships the change between two versions of any byte stream as compactly as
+// it can, for syncing content without sending all of it again.
+//
+// The encoder uses the default code table and no secondary compression, so
+// its output can be read by any VCDIFF decoder. The decoder understands the
+// output of other encoders too, except for those using custom code tables or
+// secondary compressors, and the window checksums of open-vcdiff.
+//
+// Examples:
+//
+//	patch := vcdiff.Encode(old, new)
+//	restored, err := vcdiff.Decode(old, patch)
+//		// restored is new
+package vcdiff
+
+import (
+	"errors"
+	"io"
+)
+
+var (
+	// ErrCorrupt is returned when a delta doesn't follow the format.
+	ErrCorrupt = errors.New("vcdiff: corrupt delta")
+
+	// ErrUnsupported is returned for deltas relying on features of the
+	// format not implemented here, such as secondary compression.
+	ErrUnsupported = errors.New("vcdiff: unsupported delta")
+
+	// ErrChecksum is returned when the checksum of a decoded window does
+	// not match the one stored with it.
+	ErrChecksum = errors.New("vcdiff: checksum mismatch")
+)
+
+// magic starts every delta, the last byte being the version.
+var magic = []byte{0xd6, 0xc3, 0xc4, 0x00}
+
+// Bits of the header, window and delta indicator bytes. The checksum bit of
+// the window indicator is not part of RFC 3284, but is what xdelta3 uses to
+// store an Adler-32 checksum of the target window, as four bytes, most
+// significant first. open-vcdiff uses the same bit for a checksum written
+// as a varint instead, so its checksummed deltas can't be read here, nor
+// ours there.
+const (
+	hdrDecompress = 0x01
+	hdrCodeTable  = 0x02
+	hdrAppHeader  = 0x04
+
+	winSource   = 0x01
+	winTarget   = 0x02
+	winChecksum = 0x04
+)
+
+// Instruction types of the code table.
+const (
+	noop byte = iota
+	add
+	run
+	cp
+)
+
+// code is an entry of the code table: a pair of instructions, the second of
+// which is often a noop. A size of 0 means the size follows the code in the
+// instruction section.
+type code struct {
+	typ1, size1, mode1 byte
+	typ2, size2, mode2 byte
+}
+
+// codes is the default code table from section 5.6 of the RFC, and opcodes
+// the way back from an instruction, or a pair of them, to its entry.
+var (
+	codes   = table()
+	opcodes = func() map[code]byte {
+		m := make(map[code]byte)
+		for i, c := range codes {
+			if _, ok := m[c]; !ok {
+				m[c] = byte(i)
+			}
+		}
+		return m
+	}()
+)
+
+// table builds the default code table.
+func table() (t [256]code) {
+	t[0] = code{typ1: run}
+	i := 1
+
+	for size := 0; size <= 17; size++ {
+		t[i] = code{typ1: add, size1: byte(size)}
+		i++
+	}
+	for mode := 0; mode <= 8; mode++ {
+		t[i] = code{typ1: cp, mode1: byte(mode)}
+		i++
+		for size := 4; size <= 18; size++ {
+			t[i] = code{typ1: cp, size1: byte(size), mode1: byte(mode)}
+			i++
+		}
+	}
+	for mode := 0; mode <= 5; mode++ {
+		for add1 := 1; add1 <= 4; add1++ {
+			for copy2 := 4; copy2 <= 6; copy2++ {
+				t[i] = code{add, byte(add1), 0, cp, byte(copy2), byte(mode)}
+				i++
+			}
+		}
+	}
+	for mode := 6; mode <= 8; mode++ {
+		for add1 := 1; add1 <= 4; add1++ {
+			t[i] = code{add, byte(add1), 0, cp, 4, byte(mode)}
+			i++
+		}
+	}
+	for mode := 0; mode <= 8; mode++ {
+		t[i] = code{cp, 4, byte(mode), add, 1, 0}
+		i++
+	}
+
+	return t
+}
+
+// Sizes of the address cache, the defaults of the RFC.
+const (
+	nearSize = 4
+	sameSize = 3
+)
+
+// cache is the address cache shared by the encoder and the decoder, which
+// lets COPY addresses be written relative to recently used ones.
+type cache struct {
+	near [nearSize]int
+	next int
+	same [sameSize * 256]int
+}
+
+// reset empties the cache, as is done at the start of every window.
+func (c *cache) reset() {
+	*c = cache{}
+}
+
+// update remembers an address after it has been used.
+func (c *cache) update(addr int) {
+	c.near[c.next] = addr
+	c.next = (c.next + 1) % nearSize
+	c.same[addr%len(c.same)] = addr
+}
+
+// encode picks the mode writing the address in the least bytes. A mode
+// addressing the same cache is followed by a single byte, the others by a
+// varint.
+func (c *cache) encode(addr, here int) (mode byte, value int, single bool) {
+	mode, value = 0, addr
+	if here-addr < value {
+		mode, value = 1, here-addr
+	}
+	for i, near := range c.near {
+		if addr >= near && addr-near < value {
+			mode, value = byte(2+i), addr-near
+		}
+	}
+
+	slot := addr % len(c.same)
+	if c.same[slot] == addr && varintLen(value) > 1 {
+		return byte(2 + nearSize + slot/256), slot % 256, true
+	}
+	return mode, value, false
+}
+
+// decode reads an address written in the given mode.
+func (c *cache) decode(mode byte, here int, r *reader) (int, error) {
+	var addr int
+	switch {
+	case mode == 0:
+		v, err := r.varint()
+		if err != nil {
+			return 0, err
+		}
+		addr = v
+	case mode == 1:
+		v, err := r.varint()
+		if err != nil {
+			return 0, err
+		}
+		addr = here - v
+	case int(mode) < 2+nearSize:
+		v, err := r.varint()
+		if err != nil {
+			return 0, err
+		}
+		addr = c.near[mode-2] + v
+	case int(mode) < 2+nearSize+sameSize:
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		addr = c.same[int(mode-2-nearSize)*256+int(b)]
+	default:
+		return 0, ErrCorrupt
+	}
+
+	if addr < 0 || addr >= here {
+		return 0, ErrCorrupt
+	}
+	c.update(addr)
+	return addr, nil
+}
+
+// appendVarint appends an integer in the variable length format of the RFC,
+// seven bits a byte, most significant first.
+func appendVarint(b []byte, v int) []byte {
+	var buf [10]byte
+	i := len(buf) - 1
+	buf[i] = byte(v & 0x7f)
+	for v >>= 7; v > 0; v >>= 7 {
+		i--
+		buf[i] = byte(v&0x7f) | 0x80
+	}
+	return append(b, buf[i:]...)
+}
+
+// varintLen returns how many bytes appendVarint needs for the integer.
+func varintLen(v int) int {
+	n := 1
+	for v >>= 7; v > 0; v >>= 7 {
+		n++
+	}
+	return n
+}
+
+// reader reads the sections of a delta held in memory.
+type reader struct {
+	b []byte
+}
+
+// byte reads a single byte.
+func (r *reader) byte() (byte, error) {
+	if len(r.b) == 0 {
+		return 0, ErrCorrupt
+	}
+	b := r.b[0]
+	r.b = r.b[1:]
+	return b, nil
+}
+
+// bytes reads the next n bytes.
+func (r *reader) bytes(n int) ([]byte, error) {
+	if n < 0 || n > len(r.b) {
+		return nil, ErrCorrupt
+	}
+	b := r.b[:n]
+	r.b = r.b[n:]
+	return b, nil
+}
+
+// varint reads an integer in the variable length format.
+func (r *reader) varint() (int, error) {
+	return readVarint(r.byte)
+}
+
+// readVarint reads an integer in the variable length format using the given
+// function to get the bytes.
+func readVarint(next func() (byte, error)) (int, error) {
+	v := 0
+	for i := 0; ; i++ {
+		b, err := next()
+		if err != nil {
+			if err == io.EOF {
+				err = ErrCorrupt
+			}
+			return 0, err
+		}
+		// Nothing sensible takes more than 63 bits.
+		if i == 9 {
+			return 0, ErrCorrupt
+		}
+		v = v<<7 | int(b&0x7f)
+		if b&0x80 == 0 {
+			return v, nil
+		}
+	}
+}
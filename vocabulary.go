@@ -0,0 +1,99 @@
+package delta
+
+import "sort"
+
+// Vocabulary compares the words the two revisions use, regardless of where
+// or how often, and returns the ones only prev uses and the ones only curr
+// does, in alphabetical order, for telling at a glance which terms were
+// dropped and introduced. Words are compared taking options normalizing
+// them into account, and given as they're first written.
+//
+//	delta.Vocabulary("the cat sat on the mat", "the dog sat on the rug")
+//		// []string{"cat", "mat"}, []string{"dog", "rug"}
+func Vocabulary(prev, curr string, opts ...Option) (dropped, introduced []string) {
+	o := newOptions(false, opts)
+	p, c := o.vocabulary(prev), o.vocabulary(curr)
+	for key, u := range p {
+		if _, ok := c[key]; !ok {
+			dropped = append(dropped, u.word)
+		}
+	}
+	for key, u := range c {
+		if _, ok := p[key]; !ok {
+			introduced = append(introduced, u.word)
+		}
+	}
+	sort.Strings(dropped)
+	sort.Strings(introduced)
+	return dropped, introduced
+}
+
+// Frequency tells how many times a word is used in either revision.
+type Frequency struct {
+	Word       string
+	Prev, Curr int
+}
+
+// Change returns how many times more the word is used in the current
+// revision, which is negative when it's used less.
+func (f Frequency) Change() int {
+	return f.Curr - f.Prev
+}
+
+// Frequencies counts how many times the two revisions use every word, and
+// returns the words used more or less often in curr, those changing the most
+// first, and in alphabetical order among those changing as much, for
+// tracking how often keywords are used across edits. Like Vocabulary, words
+// are compared taking options normalizing them into account, and given as
+// they're first written.
+//
+//	delta.Frequencies("go go go stop", "go stop stop")
+//		// []Frequency{{Word: "go", Prev: 3, Curr: 1}, {Word: "stop", Prev: 1, Curr: 2}}
+func Frequencies(prev, curr string, opts ...Option) []Frequency {
+	o := newOptions(false, opts)
+	p, c := o.vocabulary(prev), o.vocabulary(curr)
+
+	var fs []Frequency
+	for key, u := range p {
+		if u.n != c[key].n {
+			fs = append(fs, Frequency{u.word, u.n, c[key].n})
+		}
+	}
+	for key, u := range c {
+		if _, ok := p[key]; !ok {
+			fs = append(fs, Frequency{u.word, 0, u.n})
+		}
+	}
+	sort.Slice(fs, func(i, j int) bool {
+		a, b := abs(fs[i].Change()), abs(fs[j].Change())
+		if a != b {
+			return a > b
+		}
+		return fs[i].Word < fs[j].Word
+	})
+	return fs
+}
+
+// usage is a word as it's first written, and how many times it's used.
+type usage struct {
+	word string
+	n    int
+}
+
+// vocabulary returns the usage of the words of the text by their keys.
+func (o *options) vocabulary(text string) map[string]usage {
+	words := make(map[string]usage)
+	for _, w := range o.tokenize(text) {
+		if w == "" || o.blank(w) {
+			continue
+		}
+		key := o.key(w)
+		u, ok := words[key]
+		if !ok {
+			u.word = w
+		}
+		u.n++
+		words[key] = u
+	}
+	return words
+}
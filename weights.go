@@ -0,0 +1,57 @@
+package delta
+
+import (
+	"math/bits"
+	"unicode/utf8"
+)
+
+// WithRarityWeighting aligns the revisions on their rare and long words
+// rather than on as many words as possible, so a rewritten sentence isn't
+// stitched to the old one through every "the" and "of" the two share. Each
+// word kept counts for more the longer it is, up to 16 letters, and the
+// fewer times it shows up in both revisions together. The diff can come
+// out longer, but reads more like a person would have marked it.
+func WithRarityWeighting() Option {
+	return func(o *options) {
+		o.weighted = true
+	}
+}
+
+// weigh returns how much keeping each token of the sequences is worth.
+func weigh(prev, curr []string) map[string]int32 {
+	counts := make(map[string]int, len(prev)+len(curr))
+	for _, w := range prev {
+		counts[w]++
+	}
+	for _, w := range curr {
+		counts[w]++
+	}
+
+	total := len(prev) + len(curr)
+	weights := make(map[string]int32, len(counts))
+	for w, n := range counts {
+		// One more for every halving in how often the token shows
+		// up, compared to the whole text.
+		rarity := bits.Len(uint(total/n)) - 1
+		weights[w] = int32(max(min(utf8.RuneCountInString(w), 16), 1) * (1 + rarity))
+	}
+	return weights
+}
+
+// weighted fills in the matrix like sequence, with every kept token counting
+// for its weight rather than one.
+func weighted(c matrix, prev, curr []string, weights map[string]int32) matrix {
+	for i := 0; i <= len(prev)-1; i++ {
+		row, above := c.cells[(i+1)*c.cols:], c.cells[i*c.cols:]
+		w := weights[prev[i]]
+		for j := 0; j <= len(curr)-1; j++ {
+			if prev[i] == curr[j] {
+				row[j+1] = above[j] + w
+			} else {
+				row[j+1] = max(row[j], above[j+1])
+			}
+		}
+	}
+
+	return c
+}
@@ -0,0 +1,271 @@
+package delta
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Word break properties of Unicode Standard Annex #29, as far as Words needs
+// to tell them apart.
+type wordBreak int
+
+const (
+	wbOther wordBreak = iota
+	wbCR
+	wbLF
+	wbNewline
+	wbExtend
+	wbZWJ
+	wbRegional
+	wbFormat
+	wbKatakana
+	wbHebrew
+	wbLetter
+	wbSingleQuote
+	wbDoubleQuote
+	wbMidNumLet
+	wbMidLetter
+	wbMidNum
+	wbNumeric
+	wbExtendNumLet
+	wbSpace
+	wbComplex
+)
+
+// complexScripts holds the scripts which don't put spaces between their
+// words and are too ambiguous to split by rules, so they need a dictionary.
+var complexScripts = []*unicode.RangeTable{
+	unicode.Thai, unicode.Lao, unicode.Myanmar, unicode.Khmer,
+	unicode.Tai_Le, unicode.New_Tai_Lue, unicode.Tai_Tham, unicode.Tai_Viet,
+}
+
+// property returns the word break property of the rune. The Unicode tables
+// of the standard library don't carry it, so it's pieced together from the
+// general categories and scripts, which covers everything but a few odd
+// characters.
+func property(r rune) wordBreak {
+	switch r {
+	case '\r':
+		return wbCR
+	case '\n':
+		return wbLF
+	case '\v', '\f', 0x85, 0x2028, 0x2029:
+		return wbNewline
+	case 0x200d:
+		return wbZWJ
+	case 0x200c:
+		return wbExtend
+	case '\'':
+		return wbSingleQuote
+	case '"':
+		return wbDoubleQuote
+	case '.', 0x2018, 0x2019, 0x2024, 0xfe52, 0xff07, 0xff0e:
+		return wbMidNumLet
+	case ':', 0xb7, 0x387, 0x55f, 0x5f4, 0x2027, 0xfe13, 0xfe55, 0xff1a:
+		return wbMidLetter
+	case ',', ';', 0x37e, 0x589, 0x60c, 0x60d, 0x66c, 0x7f8, 0x2044, 0xfe10, 0xfe14, 0xfe50, 0xfe54, 0xff0c, 0xff1b:
+		return wbMidNum
+	case 0x202f:
+		return wbExtendNumLet
+	case 0xa0, 0x2007:
+		return wbOther
+	case 0x30fc, 0x309b, 0x309c, 0x30a0, 0xff70:
+		return wbKatakana
+	}
+
+	switch {
+	case r >= 0x1f1e6 && r <= 0x1f1ff:
+		return wbRegional
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc):
+		return wbExtend
+	case unicode.In(r, complexScripts...):
+		return wbComplex
+	case unicode.Is(unicode.Cf, r) && r != 0x200b:
+		return wbFormat
+	case unicode.Is(unicode.Katakana, r):
+		return wbKatakana
+	case unicode.Is(unicode.Hebrew, r) && unicode.IsLetter(r):
+		return wbHebrew
+	case unicode.IsLetter(r) && !unicode.In(r, unicode.Han, unicode.Hiragana):
+		return wbLetter
+	case unicode.Is(unicode.Nd, r):
+		return wbNumeric
+	case unicode.Is(unicode.Pc, r):
+		return wbExtendNumLet
+	case unicode.Is(unicode.Zs, r):
+		return wbSpace
+	}
+	return wbOther
+}
+
+// unit is a character together with the marks and format characters which
+// follow it, which the rules of UAX #29 skip over.
+type unit struct {
+	text   string
+	prop   wordBreak
+	zwj    bool
+	symbol bool
+}
+
+// units splits the text into units.
+func units(text string) []unit {
+	var us []unit
+	for i := 0; i < len(text); {
+		r, n := utf8.DecodeRuneInString(text[i:])
+		u := unit{prop: property(r), symbol: pictographic(r), zwj: r == 0x200d}
+
+		// Marks attach to whatever comes before them, except line
+		// breaks.
+		j := i + n
+		if u.prop != wbCR && u.prop != wbLF && u.prop != wbNewline {
+			for j < len(text) {
+				r, n := utf8.DecodeRuneInString(text[j:])
+				p := property(r)
+				if p != wbExtend && p != wbFormat && p != wbZWJ {
+					break
+				}
+				u.zwj = p == wbZWJ
+				j += n
+			}
+		}
+
+		u.text = text[i:j]
+		us = append(us, u)
+		i = j
+	}
+	return us
+}
+
+// pictographic tells whether the rune is an emoji or a similar symbol.
+func pictographic(r rune) bool {
+	return unicode.Is(unicode.So, r) || (r >= 0x1f000 && r <= 0x1faff)
+}
+
+// letter tells whether the property is one of the letters, AHLetter in the
+// terms of UAX #29.
+func letter(p wordBreak) bool {
+	return p == wbLetter || p == wbHebrew
+}
+
+// midLetter tells whether the property may join two letters.
+func midLetter(p wordBreak) bool {
+	return p == wbMidLetter || p == wbMidNumLet || p == wbSingleQuote
+}
+
+// midNum tells whether the property may join two numbers.
+func midNum(p wordBreak) bool {
+	return p == wbMidNum || p == wbMidNumLet || p == wbSingleQuote
+}
+
+// breaks tells whether there is a word boundary before us[i], following the
+// rules of UAX #29 in their order. The rule numbers are those of the
+// standard.
+func breaks(us []unit, i int) bool {
+	prev, next := us[i-1].prop, us[i].prop
+	before, after := wbOther, wbOther
+	if i > 1 {
+		before = us[i-2].prop
+	}
+	if i+1 < len(us) {
+		after = us[i+1].prop
+	}
+
+	switch {
+	case prev == wbCR && next == wbLF: // WB3
+		return false
+	case prev == wbCR || prev == wbLF || prev == wbNewline: // WB3a
+		return true
+	case next == wbCR || next == wbLF || next == wbNewline: // WB3b
+		return true
+	case us[i-1].zwj && us[i].symbol: // WB3c
+		return false
+	case prev == wbSpace && next == wbSpace: // WB3d
+		return false
+	case letter(prev) && letter(next): // WB5
+		return false
+	case letter(prev) && midLetter(next) && letter(after): // WB6
+		return false
+	case letter(before) && midLetter(prev) && letter(next): // WB7
+		return false
+	case prev == wbHebrew && next == wbSingleQuote: // WB7a
+		return false
+	case prev == wbHebrew && next == wbDoubleQuote && after == wbHebrew: // WB7b
+		return false
+	case before == wbHebrew && prev == wbDoubleQuote && next == wbHebrew: // WB7c
+		return false
+	case prev == wbNumeric && next == wbNumeric: // WB8
+		return false
+	case letter(prev) && next == wbNumeric: // WB9
+		return false
+	case prev == wbNumeric && letter(next): // WB10
+		return false
+	case before == wbNumeric && midNum(prev) && next == wbNumeric: // WB11
+		return false
+	case prev == wbNumeric && midNum(next) && after == wbNumeric: // WB12
+		return false
+	case prev == wbKatakana && next == wbKatakana: // WB13
+		return false
+	case (letter(prev) || prev == wbNumeric || prev == wbKatakana || prev == wbExtendNumLet) && next == wbExtendNumLet: // WB13a
+		return false
+	case prev == wbExtendNumLet && (letter(next) || next == wbNumeric || next == wbKatakana): // WB13b
+		return false
+	case prev == wbRegional && next == wbRegional: // WB15, WB16
+		n := 0
+		for j := i - 1; j >= 0 && us[j].prop == wbRegional; j-- {
+			n++
+		}
+		return n%2 == 0
+	}
+	return true // WB999
+}
+
+// Words returns a tokenizer splitting text at word boundaries, following the
+// rules of Unicode Standard Annex #29. Unlike splitting on spaces, it keeps
+// punctuation apart from the words, while keeping "can't", "e.g" and "3.14"
+// together, and splits Chinese and Japanese into characters.
+//
+// Thai, Lao, Khmer and Myanmar don't put spaces between their words either,
+// but can't be split by rules alone. Runs of them go to the given segmenter,
+// such as a Dictionary, or are split into characters when it's nil.
+//
+//	delta.Calculate("Don't panic!", "Don't worry!", true, delta.WithTokenizer(delta.Words(nil)))
+//		// "Don't ---panic---+++worry+++!"
+func Words(segment Segmenter) Tokenizer {
+	return func(text string) []string {
+		us := units(text)
+		var tokens []string
+
+		start := 0
+		for i := 1; i <= len(us); i++ {
+			if i < len(us) {
+				run := us[i-1].prop == wbComplex && us[i].prop == wbComplex
+				if run && segment != nil || !breaks(us, i) {
+					continue
+				}
+			}
+
+			run := joinUnits(us[start:i])
+			if segment != nil && us[start].prop == wbComplex {
+				tokens = append(tokens, segment(run)...)
+			} else {
+				tokens = append(tokens, run)
+			}
+			start = i
+		}
+
+		return tokens
+	}
+}
+
+// joinUnits puts the text of the units back together.
+func joinUnits(us []unit) string {
+	n := 0
+	for _, u := range us {
+		n += len(u.text)
+	}
+	b := make([]byte, 0, n)
+	for _, u := range us {
+		b = append(b, u.text...)
+	}
+	return string(b)
+}